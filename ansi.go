@@ -0,0 +1,16 @@
+package coyote
+
+import (
+	"regexp"
+)
+
+// ANSI escape sequence pattern.
+//
+// Matches control sequences (such as colors and cursor movement), operating
+// system commands (such as window titles) and other two-byte escapes.
+var ansiEscapePattern = regexp.MustCompile("\x1b(?:\\[[0-?]*[ -/]*[@-~]|\\][^\x07\x1b]*(?:\x07|\x1b\\\\)|[@-Z\\\\-_])")
+
+// Strip ANSI escape sequences from a line.
+func StripAnsi(line []byte) []byte {
+	return ansiEscapePattern.ReplaceAll(line, nil)
+}
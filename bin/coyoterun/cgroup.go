@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"strconv"
+)
+
+// Parse a CPU limit flag value.
+func parseCpuMaxFlag(name, value string) (float64, error) {
+	cpus, err := strconv.ParseFloat(value, 64)
+	if err != nil || cpus <= 0 || cpus*coyote.CgroupCpuPeriod < 1000 {
+		return 0, FlagParseErrorf("invalid number of CPUs for %s: %s", name, value)
+	}
+
+	return cpus, nil
+}
@@ -0,0 +1,530 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Settings whose list values are joined by commas into a single flag value.
+//
+// List values of other settings are applied as one flag per item.
+var configCommaListSettings = map[string]bool{
+	"success-exit-codes": true,
+	"ignore-signals":     true,
+	"clear-env":          true,
+	"json-level-key":     true,
+	"json-message-key":   true,
+	"json-time-key":      true,
+}
+
+// Separators between the keys and values of settings accepting mappings.
+var configMappingSeparators = map[string]string{
+	"env":          "=",
+	"restart":      ":",
+	"stop-timeout": ":",
+}
+
+// Configuration setting.
+type configSetting struct {
+	// Key path of the setting in the configuration file.
+	key string
+
+	// Equivalent flag and value.
+	flag  string
+	value string
+}
+
+// Configuration.
+type config struct {
+	// Path of the configuration file, if read from one.
+	path string
+
+	// Command.
+	command []string
+
+	// Outputs, error handlers and other settings as flags.
+	outputs       []configSetting
+	errorHandlers []configSetting
+	settings      []configSetting
+
+	// Settings unset, overriding those of sources of lower precedence
+	// without being applied.
+	unset []configSetting
+}
+
+// Apply the configuration to options.
+func (c *config) apply(o *options) error {
+	for _, s := range c.outputs {
+		if handled, err := o.addOutput(s.flag, s.value); !handled {
+			return fmt.Errorf("%s: unknown output: %s", s.key, s.flag)
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", s.key, err)
+		}
+	}
+
+	for _, s := range c.errorHandlers {
+		if handled, err := o.addErrorHandler(s.flag, s.value); !handled {
+			return fmt.Errorf("%s: unknown error handler: %s", s.key, s.flag)
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", s.key, err)
+		}
+	}
+
+	for _, s := range c.settings {
+		if err := o.set(s.flag, s.value); err == errUnknownFlag {
+			return fmt.Errorf("%s: unknown setting", s.key)
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", s.key, err)
+		}
+	}
+
+	return nil
+}
+
+// Settings overridden by a configuration source.
+//
+// Settings of sources of lower precedence are overridden by the source, with
+// outputs and error handlers each overridden as a whole and other settings by
+// flag. Settings accepting mappings are merged by key instead.
+type configOverrides struct {
+	outputs       bool
+	errorHandlers bool
+	flags         map[string]bool
+}
+
+// Add a flag set by the source.
+func (ov *configOverrides) add(flag string) {
+	for _, f := range outputFlags {
+		if flag == f.Name {
+			ov.outputs = true
+			return
+		}
+	}
+
+	for _, f := range errorHandlerFlags {
+		if flag == f.Name {
+			ov.errorHandlers = true
+			return
+		}
+	}
+
+	if _, ok := configMappingSeparators[flag]; !ok {
+		ov.flags[flag] = true
+	}
+}
+
+// New configuration overrides.
+func newConfigOverrides() *configOverrides {
+	return &configOverrides{
+		flags: make(map[string]bool),
+	}
+}
+
+// Settings overridden by the configuration.
+func (c *config) overrides() *configOverrides {
+	ov := newConfigOverrides()
+	ov.outputs = len(c.outputs) > 0
+	ov.errorHandlers = len(c.errorHandlers) > 0
+
+	for _, s := range c.settings {
+		ov.add(s.flag)
+	}
+
+	// Unsetting overrides settings accepting mappings as a whole.
+	for _, s := range c.unset {
+		ov.flags[s.flag] = true
+	}
+
+	return ov
+}
+
+// Remove settings overridden by a source of higher precedence.
+func (c *config) override(ov *configOverrides) {
+	if ov.outputs {
+		c.outputs = nil
+	}
+
+	if ov.errorHandlers {
+		c.errorHandlers = nil
+	}
+
+	settings := c.settings[:0]
+	for _, s := range c.settings {
+		if !ov.flags[s.flag] {
+			settings = append(settings, s)
+		}
+	}
+	c.settings = settings
+}
+
+// Normalize a decoded configuration value.
+//
+// Mappings are converted to map[string]interface{} and sequences to
+// []interface{}.
+func normalizeConfigValue(key string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			ks, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: invalid key: %v", key, k)
+			}
+
+			var err error
+			if m[ks], err = normalizeConfigValue(joinConfigKey(key, ks), item); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			var err error
+			if m[k], err = normalizeConfigValue(joinConfigKey(key, k), item); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case []map[string]interface{}:
+		l := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if l[i], err = normalizeConfigValue(fmt.Sprintf("%s[%d]", key, i), item); err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if l[i], err = normalizeConfigValue(fmt.Sprintf("%s[%d]", key, i), item); err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+
+	default:
+		return value, nil
+	}
+}
+
+// Join a key path and a key.
+func joinConfigKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// Sorted keys of a mapping.
+func sortedConfigKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Convert a scalar configuration value to a flag value.
+func configScalar(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case nil:
+		return "", fmt.Errorf("%s: no value provided", key)
+	default:
+		return "", fmt.Errorf("%s: expected a string or a number", key)
+	}
+}
+
+// Convert a configuration setting to flags.
+//
+// true is converted to a flag without a value, while false omits the flag.
+func configFlags(key, flag string, value interface{}) ([]configSetting, error) {
+	switch v := value.(type) {
+	case bool:
+		if !v {
+			return nil, nil
+		}
+		return []configSetting{{key, flag, ""}}, nil
+
+	case []interface{}:
+		if configCommaListSettings[flag] {
+			items := make([]string, len(v))
+			for i, item := range v {
+				var err error
+				if items[i], err = configScalar(fmt.Sprintf("%s[%d]", key, i), item); err != nil {
+					return nil, err
+				}
+			}
+
+			return []configSetting{{key, flag, strings.Join(items, ",")}}, nil
+		}
+
+		var settings []configSetting
+		for i, item := range v {
+			s, err := configScalar(fmt.Sprintf("%s[%d]", key, i), item)
+			if err != nil {
+				return nil, err
+			}
+
+			settings = append(settings, configSetting{fmt.Sprintf("%s[%d]", key, i), flag, s})
+		}
+		return settings, nil
+
+	case map[string]interface{}:
+		sep, ok := configMappingSeparators[flag]
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a string, a number or a boolean", key)
+		}
+
+		var settings []configSetting
+		for _, k := range sortedConfigKeys(v) {
+			s, err := configScalar(joinConfigKey(key, k), v[k])
+			if err != nil {
+				return nil, err
+			}
+
+			settings = append(settings, configSetting{joinConfigKey(key, k), flag, k + sep + s})
+		}
+		return settings, nil
+
+	default:
+		s, err := configScalar(key, value)
+		if err != nil {
+			return nil, err
+		}
+
+		return []configSetting{{key, flag, s}}, nil
+	}
+}
+
+// Convert a mapping of URL options to a URL.
+//
+// The mapping must contain the URL as url. All other options are added to
+// the URL as query parameters.
+func configURL(key string, m map[string]interface{}) (string, error) {
+	raw, ok := m["url"]
+	if !ok {
+		return "", fmt.Errorf("%s: no url provided", key)
+	}
+
+	base, err := configScalar(joinConfigKey(key, "url"), raw)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("%s: invalid URL: %s", joinConfigKey(key, "url"), err)
+	}
+
+	query := u.Query()
+
+	for _, k := range sortedConfigKeys(m) {
+		if k == "url" {
+			continue
+		}
+
+		values, ok := m[k].([]interface{})
+		if !ok {
+			values = []interface{}{m[k]}
+		}
+
+		for _, v := range values {
+			s, err := configScalar(joinConfigKey(key, k), v)
+			if err != nil {
+				return "", err
+			}
+
+			query.Add(k, s)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Convert a list of outputs or error handlers to flags.
+//
+// Each item is either a name or a mapping of a name to a value. The value is
+// either a flag value, true, or a mapping of URL options.
+func configNamedFlags(key string, value interface{}) ([]configSetting, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a list", key)
+	}
+
+	settings := make([]configSetting, 0, len(items))
+
+	for i, item := range items {
+		itemKey := fmt.Sprintf("%s[%d]", key, i)
+
+		switch v := item.(type) {
+		case string:
+			settings = append(settings, configSetting{itemKey, v, ""})
+
+		case map[string]interface{}:
+			if len(v) != 1 {
+				return nil, fmt.Errorf("%s: expected a single name", itemKey)
+			}
+
+			for name, value := range v {
+				valueKey := joinConfigKey(itemKey, name)
+				var s string
+				var err error
+
+				switch value := value.(type) {
+				case bool:
+					if !value {
+						return nil, fmt.Errorf("%s: expected true, a string or a mapping", valueKey)
+					}
+				case map[string]interface{}:
+					s, err = configURL(valueKey, value)
+				default:
+					s, err = configScalar(valueKey, value)
+				}
+
+				if err != nil {
+					return nil, err
+				}
+
+				settings = append(settings, configSetting{valueKey, name, s})
+			}
+
+		default:
+			return nil, fmt.Errorf("%s: expected a string or a mapping", itemKey)
+		}
+	}
+
+	return settings, nil
+}
+
+// Read a configuration file.
+//
+// The format of the file is determined by its extension, and may be YAML,
+// TOML or JSON. The command is either a list of arguments or a string which
+// is run through /bin/sh. Outputs and error handlers are lists. All other
+// settings are named like their flags.
+func readConfig(path string) (*config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &decoded)
+
+	case ".toml":
+		var m map[string]interface{}
+		_, err = toml.Decode(string(data), &m)
+		decoded = m
+
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&decoded)
+
+	default:
+		return nil, fmt.Errorf("unsupported configuration file format: %s", ext)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded, err = normalizeConfigValue("", decoded); err != nil {
+		return nil, err
+	}
+
+	root, ok := decoded.(map[string]interface{})
+	if !ok {
+		if decoded == nil {
+			return &config{path: path}, nil
+		}
+
+		return nil, fmt.Errorf("expected a mapping of settings")
+	}
+
+	c := &config{path: path}
+
+	for _, key := range sortedConfigKeys(root) {
+		value := root[key]
+
+		switch key {
+		case "command":
+			switch v := value.(type) {
+			case string:
+				c.command = []string{"/bin/sh", "-c", v}
+
+			case []interface{}:
+				for i, item := range v {
+					arg, err := configScalar(fmt.Sprintf("%s[%d]", key, i), item)
+					if err != nil {
+						return nil, err
+					}
+
+					c.command = append(c.command, arg)
+				}
+
+			default:
+				return nil, fmt.Errorf("%s: expected a string or a list", key)
+			}
+
+			if len(c.command) == 0 {
+				return nil, fmt.Errorf("%s: no command provided", key)
+			}
+
+		case "outputs":
+			if c.outputs, err = configNamedFlags(key, value); err != nil {
+				return nil, err
+			}
+
+		case "error-handlers":
+			if c.errorHandlers, err = configNamedFlags(key, value); err != nil {
+				return nil, err
+			}
+
+		default:
+			settings, err := configFlags(key, key, value)
+			if err != nil {
+				return nil, err
+			}
+
+			c.settings = append(c.settings, settings...)
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Write a configuration file to a directory.
+func writeConfig(t *testing.T, dir, name, data string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %s", path, err)
+	}
+
+	return path
+}
+
+func TestReadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coyote-config")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	expected := &config{
+		command: []string{"myapp", "--port", "8080"},
+		outputs: []configSetting{
+			{"outputs[0]", "stdout", ""},
+			{"outputs[1].syslog", "syslog", "LOCAL0:myapp"},
+			{"outputs[2].output", "output", "tcp://logs.example.com:514/token?format=json"},
+		},
+		errorHandlers: []configSetting{
+			{"error-handlers[0].on-error", "on-error", "/usr/local/bin/hook"},
+		},
+		settings: []configSetting{
+			{"env.A", "env", "A=b"},
+			{"env.PORT", "env", "PORT=8080"},
+			{"pty", "pty", ""},
+			{"success-exit-codes", "success-exit-codes", "0,3"},
+			{"tail[0]", "tail", "/var/log/a.log"},
+			{"tail[1]", "tail", "/var/log/b.log"},
+			{"timeout", "timeout", "1h"},
+		},
+	}
+
+	for name, data := range map[string]string{
+		"coyote.yaml": `
+command: [myapp, --port, 8080]
+outputs:
+  - stdout
+  - syslog: LOCAL0:myapp
+  - output:
+      url: tcp://logs.example.com:514/token
+      format: json
+error-handlers:
+  - on-error: /usr/local/bin/hook
+env:
+  PORT: 8080
+  A: b
+tail: [/var/log/a.log, /var/log/b.log]
+success-exit-codes: [0, 3]
+pty: true
+strip-ansi: false
+timeout: 1h
+`,
+		"coyote.toml": `
+command = ["myapp", "--port", "8080"]
+outputs = [
+  "stdout",
+  { syslog = "LOCAL0:myapp" },
+  { output = { url = "tcp://logs.example.com:514/token", format = "json" } },
+]
+error-handlers = [{ on-error = "/usr/local/bin/hook" }]
+tail = ["/var/log/a.log", "/var/log/b.log"]
+success-exit-codes = [0, 3]
+pty = true
+strip-ansi = false
+timeout = "1h"
+
+[env]
+PORT = 8080
+A = "b"
+`,
+		"coyote.json": `{
+  "command": ["myapp", "--port", 8080],
+  "outputs": [
+    "stdout",
+    {"syslog": "LOCAL0:myapp"},
+    {"output": {"url": "tcp://logs.example.com:514/token", "format": "json"}}
+  ],
+  "error-handlers": [{"on-error": "/usr/local/bin/hook"}],
+  "env": {"PORT": 8080, "A": "b"},
+  "tail": ["/var/log/a.log", "/var/log/b.log"],
+  "success-exit-codes": [0, 3],
+  "pty": true,
+  "strip-ansi": false,
+  "timeout": "1h"
+}`,
+	} {
+		path := writeConfig(t, dir, name, data)
+		expected.path = path
+
+		c, err := readConfig(path)
+		if err != nil {
+			t.Errorf("Expected reading %s to succeed, but got: %s", name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(c, expected) {
+			t.Errorf("Expected reading %s to result in %+v, but got %+v", name, expected, c)
+		}
+	}
+}
+
+func TestReadConfigInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coyote-config")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"coyote.yaml", "outputs: [stdout, {syslog: [LOCAL0]}]", "outputs[1].syslog: expected a string or a number"},
+		{"coyote.yaml", "outputs: [stdout, {syslog: false}]", "outputs[1].syslog: expected true, a string or a mapping"},
+		{"coyote.yaml", "outputs: [{stdout: json, syslog: true}]", "outputs[0]: expected a single name"},
+		{"coyote.yaml", "outputs: [{output: {format: json}}]", "outputs[0].output: no url provided"},
+		{"coyote.yaml", "outputs: stdout", "outputs: expected a list"},
+		{"coyote.yaml", "command: []", "command: no command provided"},
+		{"coyote.toml", "tail = [\"a\", [\"b\"]]", "tail[1]: expected a string or a number"},
+		{"coyote.toml", "[env]\nA = [\"b\"]", "env.A: expected a string or a number"},
+		{"coyote.json", `{"timeout": {"a": "b"}}`, "timeout: expected a string, a number or a boolean"},
+		{"coyote.json", `{"env": {"A": null}}`, "env.A: no value provided"},
+		{"coyote.json", `["stdout"]`, "expected a mapping of settings"},
+		{"coyote.ini", "", "unsupported configuration file format: .ini"},
+	} {
+		_, err := readConfig(writeConfig(t, dir, tc.name, tc.data))
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Expected reading %s %q to fail with %q, but got: %v", tc.name, tc.data, tc.expected, err)
+		}
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	for _, tc := range []struct {
+		flag     string
+		value    interface{}
+		expected []configSetting
+	}{
+		{"pty", true, []configSetting{{"a.b", "pty", ""}}},
+		{"pty", false, nil},
+		{"timeout", "1h", []configSetting{{"a.b", "timeout", "1h"}}},
+		{"error-retries", 3, []configSetting{{"a.b", "error-retries", "3"}}},
+		{"cgroup-cpu-max", 0.5, []configSetting{{"a.b", "cgroup-cpu-max", "0.5"}}},
+		{"ignore-signals", []interface{}{"TERM", "INT"}, []configSetting{{"a.b", "ignore-signals", "TERM,INT"}}},
+		{"tail", []interface{}{"a", "b"}, []configSetting{
+			{"a.b[0]", "tail", "a"},
+			{"a.b[1]", "tail", "b"},
+		}},
+		{"restart", map[string]interface{}{"web": "always", "worker": "no"}, []configSetting{
+			{"a.b.web", "restart", "web:always"},
+			{"a.b.worker", "restart", "worker:no"},
+		}},
+	} {
+		actual, err := configFlags("a.b", tc.flag, tc.value)
+		if err != nil {
+			t.Errorf("Expected converting %s %v to succeed, but got: %s", tc.flag, tc.value, err)
+		} else if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("Expected converting %s %v to result in %v, but got %v", tc.flag, tc.value, tc.expected, actual)
+		}
+	}
+
+	for _, tc := range []struct {
+		flag     string
+		value    interface{}
+		expected string
+	}{
+		{"timeout", nil, "a.b: no value provided"},
+		{"timeout", map[string]interface{}{"a": "b"}, "a.b: expected a string, a number or a boolean"},
+		{"tail", []interface{}{"a", true}, "a.b[1]: expected a string or a number"},
+		{"ignore-signals", []interface{}{"TERM", nil}, "a.b[1]: no value provided"},
+		{"env", map[string]interface{}{"A": []interface{}{}}, "a.b.A: expected a string or a number"},
+	} {
+		_, err := configFlags("a.b", tc.flag, tc.value)
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Expected converting %s %v to fail with %q, but got: %v", tc.flag, tc.value, tc.expected, err)
+		}
+	}
+}
+
+func TestConfigOverride(t *testing.T) {
+	c := &config{
+		outputs:       []configSetting{{"outputs[0]", "stdout", ""}},
+		errorHandlers: []configSetting{{"error-handlers[0].on-error", "on-error", "/hook"}},
+		settings: []configSetting{
+			{"env.A", "env", "A=b"},
+			{"tail[0]", "tail", "a"},
+			{"tail[1]", "tail", "b"},
+			{"timeout", "timeout", "1h"},
+		},
+	}
+
+	ov := newConfigOverrides()
+	ov.add("syslog")
+	ov.add("env")
+	ov.add("tail")
+	c.override(ov)
+
+	expected := &config{
+		errorHandlers: []configSetting{{"error-handlers[0].on-error", "on-error", "/hook"}},
+		settings: []configSetting{
+			{"env.A", "env", "A=b"},
+			{"timeout", "timeout", "1h"},
+		},
+	}
+
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected overriding to result in %+v, but got %+v", expected, c)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// Look up a user by name or ID.
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.ParseUint(name, 10, 32); err == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return u, nil
+		}
+
+		// Users without a passwd entry are allowed by ID.
+		return &user.User{Uid: name, Gid: name}, nil
+	}
+
+	return user.Lookup(name)
+}
+
+// Look up a group ID by name or ID.
+func lookupGroupId(name string) (uint32, error) {
+	if gid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(gid), nil
+	}
+
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	gid, err := strconv.ParseUint(g.Gid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid group ID for %s: %s", name, g.Gid)
+	}
+
+	return uint32(gid), nil
+}
+
+// Look up the credential to run a process with.
+//
+// The user and group may be names or IDs. If a user is provided, the process
+// runs with the primary group and supplementary groups of the user unless a
+// group is provided, and the environment defaults USER, LOGNAME and HOME are
+// returned for the user.
+func lookupCredential(userName, groupName string) (*syscall.Credential, []string, error) {
+	cred := &syscall.Credential{
+		Uid: uint32(syscall.Getuid()),
+		Gid: uint32(syscall.Getgid()),
+	}
+	var environ []string
+
+	if userName != "" {
+		u, err := lookupUser(userName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unknown user %s: %s", userName, err)
+		}
+
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid user ID for %s: %s", userName, u.Uid)
+		}
+		gid, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid group ID for %s: %s", userName, u.Gid)
+		}
+
+		cred.Uid = uint32(uid)
+		cred.Gid = uint32(gid)
+
+		if u.Username != "" {
+			groupIds, err := u.GroupIds()
+			if err == nil {
+				for _, id := range groupIds {
+					if gid, err := strconv.ParseUint(id, 10, 32); err == nil {
+						cred.Groups = append(cred.Groups, uint32(gid))
+					}
+				}
+			}
+
+			environ = append(environ, "USER="+u.Username, "LOGNAME="+u.Username)
+		}
+
+		if u.HomeDir != "" {
+			environ = append(environ, "HOME="+u.HomeDir)
+		}
+	} else {
+		// Keep the supplementary groups when only changing the group.
+		cred.NoSetGroups = true
+	}
+
+	if groupName != "" {
+		gid, err := lookupGroupId(groupName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unknown group %s: %s", groupName, err)
+		}
+
+		cred.Gid = gid
+	}
+
+	return cred, environ, nil
+}
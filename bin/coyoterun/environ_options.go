@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"github.com/nickbruun/coyote/errorhandlers"
+	"strings"
+)
+
+// Prefix of environment variables setting options.
+const environOptionPrefix = "COYOTE_"
+
+// Environment variable listing outputs.
+const environOutputsVar = "COYOTE_OUTPUTS"
+
+// Environment variable listing error handlers.
+const environErrorHandlersVar = "COYOTE_ERROR_HANDLERS"
+
+// Environment variable providing the path of a configuration file.
+const environConfigVar = "COYOTE_CONFIG"
+
+// Test if an environment variable is passed to error hooks.
+//
+// Such variables are set when coyoterun is run by an error hook, and do not
+// name options.
+func isExecEnvironVar(key string) bool {
+	for _, k := range errorhandlers.ExecEnvironVars {
+		if key == k {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Split a list of flags from an environment variable.
+//
+// Flags are separated by whitespace, and may omit the leading dash.
+func splitEnvironFlags(key, value string) []configSetting {
+	var settings []configSetting
+
+	for _, f := range strings.Fields(value) {
+		f = strings.TrimPrefix(f, "-")
+
+		s := configSetting{key: key, flag: f}
+		if equalPos := strings.IndexByte(f, '='); equalPos != -1 {
+			s.flag = f[:equalPos]
+			s.value = f[equalPos+1:]
+		}
+
+		settings = append(settings, s)
+	}
+
+	return settings
+}
+
+// Read options from environment variables.
+//
+// Outputs and error handlers are listed in COYOTE_OUTPUTS and
+// COYOTE_ERROR_HANDLERS. Other options are set by variables named like their
+// flags in upper case with underscores, prefixed by COYOTE_, for example
+// COYOTE_STOP_TIMEOUT. true sets flags without a value, false unsets the
+// option and empty values are ignored. Variables not naming an option are an
+// error, except for those passed to error hooks.
+func readEnvironConfig(environ []string) (*config, error) {
+	c := &config{}
+
+	for _, env := range environ {
+		key, value, err := splitEnvironVar(env)
+		if err != nil || !strings.HasPrefix(key, environOptionPrefix) {
+			continue
+		}
+
+		switch key {
+		case environOutputsVar:
+			c.outputs = append(c.outputs, splitEnvironFlags(key, value)...)
+
+		case environErrorHandlersVar:
+			c.errorHandlers = append(c.errorHandlers, splitEnvironFlags(key, value)...)
+
+		case environConfigVar:
+			// Read before the configuration file is applied.
+
+		default:
+			if isExecEnvironVar(key) {
+				continue
+			}
+
+			flag := strings.ToLower(strings.Replace(key[len(environOptionPrefix):], "_", "-", -1))
+			if !isOptionFlag(flag) {
+				return nil, fmt.Errorf("%s: unknown option", key)
+			}
+
+			switch value {
+			case "":
+			case "false":
+				c.unset = append(c.unset, configSetting{key, flag, ""})
+			case "true":
+				c.settings = append(c.settings, configSetting{key, flag, ""})
+			default:
+				c.settings = append(c.settings, configSetting{key, flag, value})
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// Apply options from a configuration file, if any, and environment variables.
+//
+// Settings of the configuration file are overridden by those of the
+// environment variables, and both by the flags to be applied afterwards.
+func applyEnvironOptions(o *options, environ []string, file *config, flagOverrides *configOverrides) error {
+	c, err := readEnvironConfig(environ)
+	if err != nil {
+		return err
+	}
+
+	c.override(flagOverrides)
+
+	if file != nil {
+		file.override(flagOverrides)
+		file.override(c.overrides())
+
+		if err = file.apply(o); err != nil {
+			return fmt.Errorf("%s: %s", file.path, err)
+		}
+	}
+
+	return c.apply(o)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadEnvironConfig(t *testing.T) {
+	c, err := readEnvironConfig([]string{
+		"PATH=/usr/bin:/bin",
+		"COYOTE_OUTPUTS=stdout -syslog=LOCAL0:myapp",
+		"COYOTE_ERROR_HANDLERS=on-error=/hook",
+		"COYOTE_CONFIG=/etc/coyote.yaml",
+		"COYOTE_PTY=true",
+		"COYOTE_STOP_TIMEOUT=30s",
+		"COYOTE_STRIP_ANSI=false",
+		"COYOTE_DRAIN_TIMEOUT=",
+		"COYOTE_CMD=myapp",
+		"COYOTE_EXIT_STATUS=1",
+	})
+	if err != nil {
+		t.Fatalf("Expected reading the environment to succeed, but got: %s", err)
+	}
+
+	expected := &config{
+		outputs: []configSetting{
+			{"COYOTE_OUTPUTS", "stdout", ""},
+			{"COYOTE_OUTPUTS", "syslog", "LOCAL0:myapp"},
+		},
+		errorHandlers: []configSetting{
+			{"COYOTE_ERROR_HANDLERS", "on-error", "/hook"},
+		},
+		settings: []configSetting{
+			{"COYOTE_PTY", "pty", ""},
+			{"COYOTE_STOP_TIMEOUT", "stop-timeout", "30s"},
+		},
+		unset: []configSetting{
+			{"COYOTE_STRIP_ANSI", "strip-ansi", ""},
+		},
+	}
+
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected reading the environment to result in %+v, but got %+v", expected, c)
+	}
+
+	for _, env := range []string{"COYOTE_FOO=bar", "COYOTE_STDOUT=true", "COYOTE_PTYY=true"} {
+		if _, err := readEnvironConfig([]string{env}); err == nil {
+			t.Errorf("Expected reading %s to fail", env)
+		}
+	}
+}
+
+func TestApplyEnvironOptions(t *testing.T) {
+	newFile := func() *config {
+		return &config{
+			path:    "coyote.yaml",
+			outputs: []configSetting{{"outputs[0]", "stdout", ""}},
+			settings: []configSetting{
+				{"env.A", "env", "A=file"},
+				{"env.B", "env", "B=file"},
+				{"pty", "pty", ""},
+				{"strip-ansi", "strip-ansi", ""},
+				{"tail[0]", "tail", "a"},
+				{"tail[1]", "tail", "b"},
+				{"timeout", "timeout", "1h"},
+			},
+		}
+	}
+
+	environ := []string{
+		"COYOTE_OUTPUTS=stdout=json",
+		"COYOTE_PTY=false",
+		"COYOTE_TAIL=c",
+		"COYOTE_TIMEOUT=2h",
+	}
+
+	flags := []commandLineFlag{
+		{"-stdout", "stdout", ""},
+		{"-timeout=3h", "timeout", "3h"},
+		{"-env=B=flag", "env", "B=flag"},
+	}
+
+	// The configuration file alone.
+	o := newOptions()
+	if err := applyEnvironOptions(o, nil, newFile(), newConfigOverrides()); err != nil {
+		t.Fatalf("Expected applying options to succeed, but got: %s", err)
+	}
+
+	if len(o.outputs) != 1 || !o.config.Pty || !o.config.StripAnsi || o.config.Timeout != time.Hour {
+		t.Errorf("Expected the settings of the configuration file, but got %+v", o)
+	}
+	if !reflect.DeepEqual(o.tailPatterns, []string{"a", "b"}) {
+		t.Errorf("Expected tail patterns of the configuration file, but got %q", o.tailPatterns)
+	}
+
+	// The environment overriding the configuration file.
+	o = newOptions()
+	if err := applyEnvironOptions(o, environ, newFile(), newConfigOverrides()); err != nil {
+		t.Fatalf("Expected applying options to succeed, but got: %s", err)
+	}
+
+	if len(o.outputs) != 1 || o.config.Pty || !o.config.StripAnsi || o.config.Timeout != 2*time.Hour {
+		t.Errorf("Expected the environment to take precedence, but got %+v", o)
+	}
+	if !reflect.DeepEqual(o.tailPatterns, []string{"c"}) {
+		t.Errorf("Expected tail patterns of the environment, but got %q", o.tailPatterns)
+	}
+
+	// The flags overriding both.
+	o = newOptions()
+	flagOverrides := newConfigOverrides()
+	for _, f := range flags {
+		flagOverrides.add(f.flag)
+	}
+
+	if err := applyEnvironOptions(o, environ, newFile(), flagOverrides); err != nil {
+		t.Fatalf("Expected applying options to succeed, but got: %s", err)
+	}
+	for _, f := range flags {
+		if err := o.apply(f.flag, f.value); err != nil {
+			t.Fatalf("Expected applying %s to succeed, but got: %s", f.arg, err)
+		}
+	}
+
+	if len(o.outputs) != 1 || o.config.Pty || o.config.Timeout != 3*time.Hour {
+		t.Errorf("Expected the flags to take precedence, but got %+v", o)
+	}
+	if !reflect.DeepEqual(o.environ.vars, []string{"A=file", "B=file", "B=flag"}) {
+		t.Errorf("Expected environment variables to be merged, but got %q", o.environ.vars)
+	}
+
+	// Errors of the configuration file name the file.
+	file := newFile()
+	file.settings = append(file.settings, configSetting{"orphans", "orphans", "none"})
+
+	err := applyEnvironOptions(newOptions(), nil, file, newConfigOverrides())
+	if err == nil || err.Error() != "coyote.yaml: orphans: invalid orphan policy: none" {
+		t.Errorf("Expected an error naming the configuration file, but got: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment configuration.
+type environConfig struct {
+	// Clear the environment inherited from coyoterun.
+	clear bool
+
+	// Variables kept when clearing the environment.
+	allow []string
+
+	// Paths of environment files.
+	files []string
+
+	// Variables in the form KEY=VALUE, overriding those of environment files.
+	vars []string
+}
+
+// Split an environment variable into its key and value.
+func splitEnvironVar(v string) (key, value string, err error) {
+	equalPos := strings.IndexByte(v, '=')
+	if equalPos < 1 {
+		return "", "", fmt.Errorf("invalid environment variable: %s", v)
+	}
+
+	return v[:equalPos], v[equalPos+1:], nil
+}
+
+// Read an environment file.
+//
+// Each non-empty line not starting with # sets a variable in the form
+// KEY=VALUE, optionally prefixed by export. Values may be enclosed in single
+// quotes, which are removed, or double quotes, in which escape sequences are
+// interpreted.
+func readEnvironFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vars []string
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		key, value, err := splitEnvironVar(strings.TrimPrefix(line, "export "))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err)
+		}
+
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		} else if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			if value, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value for %s", lineNo, key)
+			}
+		}
+
+		vars = append(vars, key+"="+value)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// Test if the configuration changes the inherited environment.
+func (c *environConfig) changed() bool {
+	return c.clear || len(c.files) > 0 || len(c.vars) > 0
+}
+
+// Build the environment.
+//
+// The defaults, in the form KEY=VALUE, override the inherited environment,
+// but not variables set by environment files or explicitly.
+func (c *environConfig) build(defaults []string) ([]string, error) {
+	var keys []string
+	values := make(map[string]string)
+
+	set := func(v string) error {
+		key, value, err := splitEnvironVar(v)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+
+		return nil
+	}
+
+	// Start from the inherited environment, keeping only allowed variables
+	// when clearing it.
+	allowed := make(map[string]bool, len(c.allow))
+	for _, key := range c.allow {
+		allowed[key] = true
+	}
+
+	for _, v := range os.Environ() {
+		key, _, err := splitEnvironVar(v)
+		if err != nil || (c.clear && !allowed[key]) {
+			continue
+		}
+
+		set(v)
+	}
+
+	for _, v := range defaults {
+		set(v)
+	}
+
+	for _, path := range c.files {
+		vars, err := readEnvironFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read environment file %s: %s", path, err)
+		}
+
+		for _, v := range vars {
+			set(v)
+		}
+	}
+
+	for _, v := range c.vars {
+		if err := set(v); err != nil {
+			return nil, err
+		}
+	}
+
+	environ := make([]string, len(keys))
+	for i, key := range keys {
+		environ[i] = key + "=" + values[key]
+	}
+
+	return environ, nil
+}
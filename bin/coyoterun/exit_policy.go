@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Parse success exit codes.
+func parseSuccessExitCodes(p *coyote.ExitPolicy, value string) error {
+	p.SuccessExitCodes = make(map[int]bool)
+
+	for _, s := range strings.Split(value, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || code < 0 || code > 255 {
+			return FlagParseErrorf("invalid exit code: %s", s)
+		}
+
+		p.SuccessExitCodes[code] = true
+	}
+
+	return nil
+}
+
+// Parse ignored signals.
+func parseIgnoredSignals(p *coyote.ExitPolicy, value string) error {
+	p.IgnoredSignals = make(map[syscall.Signal]bool)
+
+	for _, s := range strings.Split(value, ",") {
+		sig, err := parseSignal(s)
+		if err != nil {
+			return err
+		}
+
+		p.IgnoredSignals[sig] = true
+	}
+
+	return nil
+}
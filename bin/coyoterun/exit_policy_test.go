@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestParseSuccessExitCodes(t *testing.T) {
+	for value, expected := range map[string]map[int]bool{
+		"3":       {3: true},
+		"0,3,255": {0: true, 3: true, 255: true},
+		" 1 , 2 ": {1: true, 2: true},
+		"1,1":     {1: true},
+	} {
+		p := &coyote.ExitPolicy{}
+		if err := parseSuccessExitCodes(p, value); err != nil {
+			t.Errorf("Expected parsing %q to succeed, but got: %s", value, err)
+		} else if !reflect.DeepEqual(p.SuccessExitCodes, expected) {
+			t.Errorf("Expected parsing %q to result in %v, but got %v", value, expected, p.SuccessExitCodes)
+		}
+	}
+
+	for _, value := range []string{"", "1,", ",1", "256", "-1", "a", "1.5", "0x1"} {
+		if err := parseSuccessExitCodes(&coyote.ExitPolicy{}, value); err == nil {
+			t.Errorf("Expected parsing %q to fail", value)
+		} else if _, ok := err.(*FlagParseError); !ok {
+			t.Errorf("Expected parsing %q to fail with a flag parse error, but got: %s", value, err)
+		}
+	}
+}
+
+func TestParseIgnoredSignals(t *testing.T) {
+	for value, expected := range map[string]map[syscall.Signal]bool{
+		"TERM":         {syscall.SIGTERM: true},
+		"TERM,int":     {syscall.SIGTERM: true, syscall.SIGINT: true},
+		"SIGUSR1, 9":   {syscall.SIGUSR1: true, syscall.SIGKILL: true},
+		"sigpipe,PIPE": {syscall.SIGPIPE: true},
+	} {
+		p := &coyote.ExitPolicy{}
+		if err := parseIgnoredSignals(p, value); err != nil {
+			t.Errorf("Expected parsing %q to succeed, but got: %s", value, err)
+		} else if !reflect.DeepEqual(p.IgnoredSignals, expected) {
+			t.Errorf("Expected parsing %q to result in %v, but got %v", value, expected, p.IgnoredSignals)
+		}
+	}
+
+	for _, value := range []string{"", "TERM,", ",TERM", "BOGUS", "SIG", "0", "-9"} {
+		if err := parseIgnoredSignals(&coyote.ExitPolicy{}, value); err == nil {
+			t.Errorf("Expected parsing %q to fail", value)
+		} else if _, ok := err.(*FlagParseError); !ok {
+			t.Errorf("Expected parsing %q to fail with a flag parse error, but got: %s", value, err)
+		}
+	}
+}
+
+func TestIsForwardedSignal(t *testing.T) {
+	for sig, expected := range map[syscall.Signal]bool{
+		syscall.SIGTERM: true,
+		syscall.SIGHUP:  true,
+		syscall.SIGUSR1: true,
+		syscall.SIGPIPE: false,
+	} {
+		if actual := isForwardedSignal(sig); actual != expected {
+			t.Errorf("Expected forwarding %s to be %t, but got %t", sig, expected, actual)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Flag parse error.
@@ -17,3 +20,66 @@ func (e *FlagParseError) Error() string {
 func FlagParseErrorf(format string, a ...interface{}) error {
 	return &FlagParseError{fmt.Sprintf(format, a...)}
 }
+
+// Command line flag.
+type commandLineFlag struct {
+	// Argument the flag was parsed from.
+	arg string
+
+	// Flag name and value.
+	flag, value string
+}
+
+// Parse a duration flag value.
+func parseDurationFlag(name, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, FlagParseErrorf("invalid duration for %s: %s", name, value)
+	}
+
+	return d, nil
+}
+
+// Parse a non-negative integer flag value.
+func parseCountFlag(name, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, FlagParseErrorf("invalid count for %s: %s", name, value)
+	}
+
+	return n, nil
+}
+
+// Byte size suffixes.
+var byteSizeSuffixes = []struct {
+	suffix     string
+	multiplier uint64
+}{
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"T", 1 << 40},
+}
+
+// Parse a byte size flag value.
+//
+// The size may have a K, M, G or T suffix for a multiple of 1024 bytes.
+func parseByteSizeFlag(name, value string) (uint64, error) {
+	number := strings.ToUpper(value)
+	multiplier := uint64(1)
+
+	for _, s := range byteSizeSuffixes {
+		if strings.HasSuffix(number, s.suffix) {
+			number = number[:len(number)-len(s.suffix)]
+			multiplier = s.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil || n > (1<<64-1)/multiplier {
+		return 0, FlagParseErrorf("invalid size for %s: %s", name, value)
+	}
+
+	return n * multiplier, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"github.com/nickbruun/coyote/input"
+	"github.com/nickbruun/coyote/output"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// Run inputs.
+//
+// Sinks lines from the inputs to the outputs until coyoterun is asked to stop
+// or an input fails. Returns the exit status.
+func runInputs(inputs []input.Input, outputs []output.Output, filter func([]byte) []byte) int {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	errs := make(chan error, len(inputs))
+
+	for _, in := range inputs {
+		go func(in input.Input) {
+			errs <- in.Run(func(line []byte) {
+				if filter != nil {
+					line = filter(line)
+				}
+
+				sinkLine(line, outputs)
+			})
+		}(in)
+	}
+
+	// Wait until asked to stop or an input stops by itself.
+	exitStatus := 0
+	running := len(inputs)
+
+	select {
+	case <-sigs:
+	case err := <-errs:
+		running--
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Input failed: %s\n", err)
+			exitStatus = 1
+		}
+	}
+
+	// Stop the inputs.
+	for _, in := range inputs {
+		in.Close()
+	}
+
+	for ; running > 0; running-- {
+		if err := <-errs; err != nil {
+			fmt.Fprintf(os.Stderr, "Input failed: %s\n", err)
+		}
+	}
+
+	return exitStatus
+}
+
+// Parse a syslog listener flag value of the form <network>://<address>.
+func parseSyslogListenFlag(value string) (network, address string, err error) {
+	sep := strings.Index(value, "://")
+	if sep == -1 {
+		return "", "", FlagParseErrorf("invalid syslog listener: %s", value)
+	}
+
+	network, address = value[:sep], value[sep+3:]
+
+	switch network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return "", "", FlagParseErrorf("invalid syslog listener network: %s", network)
+	}
+
+	if address == "" {
+		return "", "", FlagParseErrorf("no address provided for syslog listener.")
+	}
+
+	return network, address, nil
+}
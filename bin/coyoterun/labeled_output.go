@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"github.com/nickbruun/coyote/output"
+	"time"
+)
+
+// Colors used for labels, cycled through in order.
+var labelColors = []int{36, 33, 32, 35, 34, 31, 96, 93, 92, 95, 94, 91}
+
+// Labeled output.
+//
+// Sets the process of records before sinking them to a shared output, which
+// is left open when the labeled output is closed. Lines are prefixed with a
+// label unless the output includes the process in them itself.
+type labeledOutput struct {
+	output  output.Output
+	process string
+	prefix  []byte
+}
+
+// Label a line.
+func (o *labeledOutput) label(line []byte) []byte {
+	l := make([]byte, 0, len(o.prefix)+len(line))
+	l = append(l, o.prefix...)
+	return append(l, line...)
+}
+
+func (o *labeledOutput) Sink(line []byte) {
+	o.SinkRecord(output.Record{Time: time.Now(), Line: line})
+}
+
+func (o *labeledOutput) SinkRecord(r output.Record) {
+	r.Process = o.process
+	if !output.IncludesProcess(o.output) {
+		r.Line = o.label(r.Line)
+	}
+
+	output.SinkRecord(o.output, r)
+}
+
+func (o *labeledOutput) Close() error {
+	return nil
+}
+
+// Label outputs.
+//
+// The label is padded to the width. On outputs writing to a terminal, the
+// label is colored by the color index.
+func labelOutputs(outputs []output.Output, label string, width int, colorIndex int) []output.Output {
+	plain := fmt.Sprintf("%-*s | ", width, label)
+	colored := fmt.Sprintf("\x1b[%dm%-*s |\x1b[0m ", labelColors[colorIndex%len(labelColors)], width, label)
+
+	labeled := make([]output.Output, len(outputs))
+
+	for i, o := range outputs {
+		prefix := plain
+		if output.IsTerminal(o) {
+			prefix = colored
+		}
+
+		labeled[i] = &labeledOutput{
+			output:  o,
+			process: label,
+			prefix:  []byte(prefix),
+		}
+	}
+
+	return labeled
+}
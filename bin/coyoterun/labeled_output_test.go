@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/nickbruun/coyote/output"
+	"sync"
+	"testing"
+)
+
+// Output recording sunk records.
+type recordingOutput struct {
+	records []output.Record
+	mutex   sync.Mutex
+}
+
+func (o *recordingOutput) Sink(line []byte) {
+	o.SinkRecord(output.Record{Line: line})
+}
+
+func (o *recordingOutput) SinkRecord(r output.Record) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.records = append(o.records, r)
+}
+
+// Lines of the sunk records.
+func (o *recordingOutput) lines() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	lines := make([]string, len(o.records))
+	for i, r := range o.records {
+		lines[i] = string(r.Line)
+	}
+
+	return lines
+}
+
+func (o *recordingOutput) Close() error {
+	return nil
+}
+
+func TestLabelOutputs(t *testing.T) {
+	recorded := &recordingOutput{}
+	formatted := output.NewFormattedOutput(recorded, output.FormatConfig{Format: output.FormatJson})
+	plain := &recordingOutput{}
+
+	labeled := labelOutputs([]output.Output{formatted, plain}, "web", 6, 0)
+	for _, o := range labeled {
+		output.SinkRecord(o, output.Record{Line: []byte("listening")})
+	}
+
+	if len(plain.records) != 1 || string(plain.records[0].Line) != "web    | listening" || plain.records[0].Process != "web" {
+		t.Errorf("Expected a labeled line, but got %+v", plain.records)
+	}
+
+	if len(recorded.records) != 1 {
+		t.Fatalf("Expected a single formatted line, but got %+v", recorded.records)
+	}
+
+	var formattedRecord map[string]interface{}
+	if err := json.Unmarshal(recorded.records[0].Line, &formattedRecord); err != nil {
+		t.Fatalf("Expected a JSON line, but got %q", recorded.records[0].Line)
+	}
+	if formattedRecord["process"] != "web" || formattedRecord["msg"] != "listening" {
+		t.Errorf("Expected the process and an unlabeled message, but got %q", recorded.records[0].Line)
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"errors"
+	"github.com/nickbruun/coyote"
+	"github.com/nickbruun/coyote/cron"
+	"github.com/nickbruun/coyote/errorhandlers"
+	"github.com/nickbruun/coyote/output"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Unknown flag error.
+var errUnknownFlag = errors.New("unknown flag")
+
+// Options.
+//
+// Options are set from flags, either provided on the command line or derived
+// from the settings of a configuration file.
+type options struct {
+	outputs          []output.Output
+	errorHandlers    []errorhandlers.Handler
+	dispatcherConfig errorhandlers.DispatcherConfig
+	config           coyote.SupervisorConfig
+	drainTimeout     time.Duration
+	parseJson        bool
+	pipe             bool
+	jsonParseConfig  output.JsonParseConfig
+	initMode         bool
+	tailPatterns     []string
+	tailStatePath    string
+	syslogListeners  [][2]string
+	procfilePath     string
+	restarts         map[string]coyote.RestartPolicy
+	stopTimeouts     map[string]time.Duration
+	keepRunning      bool
+	schedule         *cron.Schedule
+	scheduleJitter   time.Duration
+	overlap          overlapPolicy
+	userName         string
+	groupName        string
+	environ          environConfig
+	dedupWindow      time.Duration
+	dedupStatePath   string
+}
+
+// Add an output from an output flag.
+//
+// Returns false if the flag is not an output flag.
+func (o *options) addOutput(flag, value string) (bool, error) {
+	for _, f := range outputFlags {
+		if flag == f.Name {
+			out, err := f.Parse(value)
+			if err != nil {
+				return true, err
+			}
+
+			o.outputs = append(o.outputs, out)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Add an error handler from an error handler flag.
+//
+// Returns false if the flag is not an error handler flag.
+func (o *options) addErrorHandler(flag, value string) (bool, error) {
+	for _, f := range errorHandlerFlags {
+		if flag == f.Name {
+			h, err := f.Parse(value)
+			if err != nil {
+				return true, err
+			}
+
+			o.errorHandlers = append(o.errorHandlers, h)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Set an option from a flag.
+//
+// Output and error handler flags are not handled. Returns errUnknownFlag if
+// the flag is unknown.
+func (o *options) set(flag, value string) error {
+	var err error
+
+	switch flag {
+	case "success-exit-codes":
+		if err = parseSuccessExitCodes(&o.config.ExitPolicy, value); err != nil {
+			return err
+		}
+
+	case "ignore-signals":
+		if err = parseIgnoredSignals(&o.config.ExitPolicy, value); err != nil {
+			return err
+		}
+
+	case "stop-timeout":
+		name, value := splitProcessFlagValue(value)
+
+		stopTimeout, err := parseDurationFlag(flag, value)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			o.config.StopTimeout = stopTimeout
+		} else {
+			o.stopTimeouts[name] = stopTimeout
+		}
+
+	case "user":
+		if value == "" {
+			return FlagParseErrorf("no user provided.")
+		}
+		o.userName = value
+
+	case "group":
+		if value == "" {
+			return FlagParseErrorf("no group provided.")
+		}
+		o.groupName = value
+
+	case "chdir":
+		if value == "" {
+			return FlagParseErrorf("no directory provided for chdir.")
+		}
+		o.config.Dir = value
+
+	case "env":
+		if _, _, err = splitEnvironVar(value); err != nil {
+			return FlagParseErrorf("%s", err)
+		}
+		o.environ.vars = append(o.environ.vars, value)
+
+	case "env-file":
+		if value == "" {
+			return FlagParseErrorf("no path provided for environment file.")
+		}
+		o.environ.files = append(o.environ.files, value)
+
+	case "clear-env":
+		o.environ.clear = true
+		if value != "" {
+			for _, key := range strings.Split(value, ",") {
+				if key = strings.TrimSpace(key); key != "" {
+					o.environ.allow = append(o.environ.allow, key)
+				}
+			}
+		}
+
+	case "rlimit-nofile", "rlimit-core", "rlimit-as":
+		var limit coyote.Rlimit
+
+		switch flag {
+		case "rlimit-nofile":
+			limit, err = parseRlimit(flag, "open files", syscall.RLIMIT_NOFILE, true, value)
+		case "rlimit-core":
+			limit, err = parseRlimit(flag, "core file size", syscall.RLIMIT_CORE, false, value)
+		case "rlimit-as":
+			limit, err = parseRlimit(flag, "address space", syscall.RLIMIT_AS, false, value)
+		}
+
+		if err != nil {
+			return err
+		}
+		o.config.Rlimits = append(o.config.Rlimits, limit)
+
+	case "cgroup-parent":
+		if value == "" {
+			return FlagParseErrorf("no path provided for cgroup parent.")
+		}
+		o.config.Cgroup.Parent = value
+
+	case "cgroup-memory-max":
+		if o.config.Cgroup.MemoryMax, err = parseByteSizeFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "cgroup-cpu-max":
+		if o.config.Cgroup.CpuMax, err = parseCpuMaxFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "timeout":
+		if o.config.Timeout, err = parseDurationFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "output-watchdog":
+		if o.config.OutputWatchdog, err = parseDurationFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "output-watchdog-stop":
+		if value != "" {
+			return FlagParseErrorf("output-watchdog-stop does not accept a value.")
+		}
+		o.config.StopStalled = true
+
+	case "schedule":
+		if o.schedule, err = cron.Parse(value); err != nil {
+			return FlagParseErrorf("invalid schedule: %s", err)
+		}
+
+	case "schedule-jitter":
+		if o.scheduleJitter, err = parseDurationFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "schedule-overlap":
+		if o.overlap, err = parseOverlapPolicy(value); err != nil {
+			return err
+		}
+
+	case "procfile":
+		if value == "" {
+			return FlagParseErrorf("no path provided for procfile.")
+		}
+		o.procfilePath = value
+
+	case "restart":
+		name, value := splitProcessFlagValue(value)
+
+		rp, err := parseRestartPolicy(value)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			o.config.Restart = rp
+		} else {
+			o.restarts[name] = rp
+		}
+
+	case "on-process-exit":
+		switch value {
+		case "stop-all":
+			o.keepRunning = false
+		case "keep-running":
+			o.keepRunning = true
+		default:
+			return FlagParseErrorf("invalid process exit behavior: %s", value)
+		}
+
+	case "init":
+		if value != "" {
+			return FlagParseErrorf("init does not accept a value.")
+		}
+		o.initMode = true
+
+	case "pty":
+		if value != "" {
+			return FlagParseErrorf("pty does not accept a value.")
+		}
+		o.config.Pty = true
+
+	case "strip-ansi":
+		if value != "" {
+			return FlagParseErrorf("strip-ansi does not accept a value.")
+		}
+		o.config.StripAnsi = true
+
+	case "parse-json":
+		if value != "" {
+			return FlagParseErrorf("parse-json does not accept a value.")
+		}
+		o.parseJson = true
+
+	case "json-level-key", "json-message-key", "json-time-key":
+		var keys []string
+		for _, k := range strings.Split(value, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys = append(keys, k)
+			}
+		}
+
+		if len(keys) == 0 {
+			return FlagParseErrorf("no keys provided for %s.", flag)
+		}
+
+		switch flag {
+		case "json-level-key":
+			o.jsonParseConfig.LevelKeys = keys
+		case "json-message-key":
+			o.jsonParseConfig.MessageKeys = keys
+		case "json-time-key":
+			o.jsonParseConfig.TimeKeys = keys
+		}
+
+	case "stdin":
+		if value != "" {
+			return FlagParseErrorf("stdin does not accept a value.")
+		}
+		o.config.Stdin = true
+
+	case "pipe":
+		if value != "" {
+			return FlagParseErrorf("pipe does not accept a value.")
+		}
+		o.pipe = true
+
+	case "orphans":
+		if o.config.Orphans, err = parseOrphanPolicy(value); err != nil {
+			return err
+		}
+
+	case "drain-timeout":
+		if o.drainTimeout, err = parseDurationFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "tail":
+		if value == "" {
+			return FlagParseErrorf("no pattern provided for tail.")
+		}
+		o.tailPatterns = append(o.tailPatterns, value)
+
+	case "tail-state":
+		if value == "" {
+			return FlagParseErrorf("no path provided for tail state.")
+		}
+		o.tailStatePath = value
+
+	case "syslog-listen":
+		network, address, err := parseSyslogListenFlag(value)
+		if err != nil {
+			return err
+		}
+		o.syslogListeners = append(o.syslogListeners, [2]string{network, address})
+
+	case "error-timeout":
+		if o.dispatcherConfig.Timeout, err = parseDurationFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "error-retries":
+		if o.dispatcherConfig.Retries, err = parseCountFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "error-deadline":
+		if o.dispatcherConfig.Deadline, err = parseDurationFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "error-spool":
+		if value == "" {
+			return FlagParseErrorf("no directory provided for error spool.")
+		}
+		o.dispatcherConfig.SpoolDir = value
+
+	case "error-dedup-window":
+		if o.dedupWindow, err = parseDurationFlag(flag, value); err != nil {
+			return err
+		}
+
+	case "error-dedup-state":
+		if value == "" {
+			return FlagParseErrorf("no path provided for error deduplication state.")
+		}
+		o.dedupStatePath = value
+
+	default:
+		return errUnknownFlag
+	}
+
+	return nil
+}
+
+// Test if a flag sets an option.
+//
+// Output and error handler flags do not set options.
+func isOptionFlag(flag string) bool {
+	return newOptions().set(flag, "") != errUnknownFlag
+}
+
+// Apply a flag.
+//
+// Returns errUnknownFlag if the flag is unknown.
+func (o *options) apply(flag, value string) error {
+	if handled, err := o.addOutput(flag, value); handled {
+		return err
+	}
+
+	if handled, err := o.addErrorHandler(flag, value); handled {
+		return err
+	}
+
+	return o.set(flag, value)
+}
+
+// New options with defaults.
+func newOptions() *options {
+	o := &options{
+		restarts:     make(map[string]coyote.RestartPolicy),
+		stopTimeouts: make(map[string]time.Duration),
+	}
+	o.dispatcherConfig.Retries = 3
+
+	return o
+}
@@ -3,10 +3,8 @@ package main
 import (
 	"fmt"
 	"github.com/nickbruun/coyote/output"
-	"log/syslog"
 	"net/url"
 	"strings"
-	"time"
 )
 
 // Output flag.
@@ -21,16 +19,94 @@ type OutputFlag struct {
 	Parse func(value string) (output.Output, error)
 }
 
+// Open an output from a URL for a flag.
+//
+// If schemes are provided, the URL must have one of them.
+func openOutputFlag(desc, value string, schemes ...string) (output.Output, error) {
+	if value == "" {
+		return nil, FlagParseErrorf("no URL provided for %s.", desc)
+	}
+
+	if len(schemes) > 0 {
+		u, err := url.Parse(value)
+		if err != nil {
+			return nil, FlagParseErrorf("invalid URL provided for %s: %s", desc, err)
+		}
+
+		valid := false
+		for _, s := range schemes {
+			valid = valid || u.Scheme == s
+		}
+
+		if !valid {
+			return nil, FlagParseErrorf("invalid URL scheme for %s: %s", desc, u.Scheme)
+		}
+	}
+
+	o, err := output.Open(value)
+	if _, ok := err.(*output.InvalidURLError); ok {
+		return nil, FlagParseErrorf("invalid %s: %s", desc, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
 // Output flags.
 var outputFlags = []OutputFlag{
+	// Output by URL.
+	OutputFlag{
+		Name: "output",
+		Usage: `-output=<url>
+    Add an output by URL. The scheme selects the kind of output, and can be
+    one of:
+
+        stdout:
+        tcp[s]://<host>:<port>/<token>[?timeout=<duration>]
+        syslog://[<host>:<port>][?facility=<facility>][&tag=<tag>][&network=udp|tcp]
+
+    tcp and tcps add token-based TCP outputs like -token-based-tcp, where
+    the timeout of connecting defaults to 5s. syslog adds a syslog output
+    like -syslog, sending to the syslog daemon at the host over the
+    network, which defaults to udp, if a host is provided.
+
+    Lines are sent as is unless a format is provided by the format query
+    parameter of any URL:
+
+        raw          Lines as is.
+        timestamped  Lines prefixed by a timestamp.
+        logfmt       Lines as key=value pairs of ts, stream, host,
+                     process, cmd and msg.
+        json         Lines as JSON objects of ts, stream, host, process,
+                     cmd and msg.
+
+    Lines are formatted before being framed by the output, for example
+    prefixed by the token of token-based TCP outputs. Timestamps are in
+    local time with millisecond precision, unless the layout is provided
+    by the time-layout query parameter in the format of Go's time package,
+    for example 2006-01-02 15:04:05, or utc=true is provided. For example:
+
+        stdout:?format=timestamped&utc=true`,
+		Parse: func(value string) (output.Output, error) {
+			return openOutputFlag("output", value)
+		},
+	},
+
 	// Stdout.
 	OutputFlag{
 		Name: "stdout",
-		Usage: `-stdout
-    Add a stdout output.`,
+		Usage: `-stdout[=<format>]
+    Add a stdout output, optionally formatting lines in one of the formats
+    of -output. Use -output for other format options.`,
 		Parse: func(value string) (output.Output, error) {
+			var format output.Format
+
 			if value != "" {
-				return nil, FlagParseErrorf("stdout does not accept a value")
+				var err error
+				if format, err = output.ParseFormat(value); err != nil {
+					return nil, FlagParseErrorf("%s", err)
+				}
 			}
 
 			o, err := output.NewStdoutOutput()
@@ -38,7 +114,7 @@ var outputFlags = []OutputFlag{
 				return nil, fmt.Errorf("Failed to create stdout output: %s\n", err)
 			}
 
-			return o, nil
+			return output.NewFormattedOutput(o, output.FormatConfig{Format: format}), nil
 		},
 	},
 
@@ -52,36 +128,7 @@ var outputFlags = []OutputFlag{
 
         tcps://api.logentries.com:20000/2bfbea1e-10c3-4419-bdad-7e6435882e1f`,
 		Parse: func(value string) (output.Output, error) {
-			if value == "" {
-				return nil, FlagParseErrorf("no URL provided for token-based TCP output.")
-			}
-
-			url, err := url.Parse(value)
-			if err != nil {
-				return nil, FlagParseErrorf("Error: invalid URL provided for token-based TCP output: %s", err)
-			}
-
-			var ssl bool
-
-			if url.Scheme == "tcp" {
-				ssl = false
-			} else if url.Scheme == "tcps" {
-				ssl = true
-			} else {
-				return nil, FlagParseErrorf("invalid URL scheme for token-based TCP output: %s", url.Scheme)
-			}
-
-			token := url.Path[1:]
-			if token == "" {
-				return nil, FlagParseErrorf("no token specified for token-based TCP output.")
-			}
-
-			o, err := output.NewTokenBasedTcpOutput(url.Host, token, 5*time.Second, ssl)
-			if err != nil {
-				return nil, fmt.Errorf("Failed to create token-based TCP output: %s\n", err)
-			}
-
-			return o, nil
+			return openOutputFlag("token-based TCP output", value, "tcp", "tcps")
 		},
 	},
 
@@ -95,63 +142,26 @@ var outputFlags = []OutputFlag{
     is provided, the output defaults to LOCAL0. The tag will be prefixed
      to any log line.`,
 		Parse: func(value string) (output.Output, error) {
-			var facilityName, tag string
+			var facility, tag string
 			colonPos := strings.IndexByte(value, ':')
 
 			if colonPos == -1 {
-				facilityName = value
+				facility = value
 			} else {
-				facilityName = value[:colonPos]
+				facility = value[:colonPos]
 				tag = value[colonPos+1:]
 			}
 
-			var facility syslog.Priority
-			switch strings.ToUpper(facilityName) {
-			case "KERN":
-				facility = syslog.LOG_KERN
-			case "USER":
-				facility = syslog.LOG_USER
-			case "MAIL":
-				facility = syslog.LOG_MAIL
-			case "DAEMON":
-				facility = syslog.LOG_DAEMON
-			case "AUTH":
-				facility = syslog.LOG_AUTH
-			case "SYSLOG":
-				facility = syslog.LOG_SYSLOG
-			case "LPR":
-				facility = syslog.LOG_LPR
-			case "NEWS":
-				facility = syslog.LOG_NEWS
-			case "UUCP":
-				facility = syslog.LOG_UUCP
-			case "CRON":
-				facility = syslog.LOG_CRON
-			case "AUTHPRIV":
-				facility = syslog.LOG_AUTHPRIV
-			case "FTP":
-				facility = syslog.LOG_FTP
-			case "", "LOCAL0":
-				facility = syslog.LOG_LOCAL0
-			case "LOCAL1":
-				facility = syslog.LOG_LOCAL1
-			case "LOCAL2":
-				facility = syslog.LOG_LOCAL2
-			case "LOCAL3":
-				facility = syslog.LOG_LOCAL3
-			case "LOCAL4":
-				facility = syslog.LOG_LOCAL4
-			case "LOCAL5":
-				facility = syslog.LOG_LOCAL5
-			case "LOCAL6":
-				facility = syslog.LOG_LOCAL6
-			case "LOCAL7":
-				facility = syslog.LOG_LOCAL7
-			default:
-				return nil, FlagParseErrorf("invalid syslog facility: %s", facilityName)
+			if _, err := output.ParseSyslogFacility(facility); err != nil {
+				return nil, FlagParseErrorf("%s", err)
+			}
+
+			u := url.URL{
+				Scheme:   "syslog",
+				RawQuery: url.Values{"facility": {facility}, "tag": {tag}}.Encode(),
 			}
 
-			o, err := output.NewSyslogOutput("", "", facility, tag)
+			o, err := output.Open(u.String())
 			if err != nil {
 				return nil, fmt.Errorf("Failed to set up syslog output: %s", err)
 			}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"github.com/nickbruun/coyote/output"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Pipe stdin to outputs.
+//
+// Sinks the lines read from the stdin of coyoterun until it is exhausted or
+// coyoterun is asked to stop.
+func pipeStdin(outputs []output.Output, filter func([]byte) []byte) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	drained := make(chan struct{})
+	go func() {
+		coyote.Drain(os.Stdin, outputs, filter)
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-sigs:
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"github.com/nickbruun/coyote/output"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Parse an orphan policy.
+func parseOrphanPolicy(value string) (coyote.OrphanPolicy, error) {
+	switch value {
+	case "term":
+		return coyote.OrphanTerm, nil
+	case "kill":
+		return coyote.OrphanKill, nil
+	case "wait":
+		return coyote.OrphanWait, nil
+	default:
+		return 0, FlagParseErrorf("invalid orphan policy: %s", value)
+	}
+}
+
+// Notify a channel of the signals handled by coyoterun.
+func notifySignals(sigs chan<- os.Signal) {
+	signal.Notify(sigs, syscall.SIGABRT, syscall.SIGALRM, syscall.SIGFPE, syscall.SIGHUP, syscall.SIGILL, syscall.SIGINT, syscall.SIGPIPE, syscall.SIGQUIT, syscall.SIGSEGV, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
+}
+
+// Run a process.
+//
+// Forwards signals to the process while it runs. Returns the exit status of
+// the process.
+func runProcess(args []string, outputs []output.Output, config coyote.SupervisorConfig) int {
+	s := coyote.NewSupervisor(args, outputs, config)
+
+	// Forward signals to the process.
+	sigs := make(chan os.Signal, 1)
+	notifySignals(sigs)
+	defer signal.Stop(sigs)
+
+	go func() {
+		for sig := range sigs {
+			if !isForwardedSignal(sig) {
+				continue
+			}
+
+			if coyote.IsStopSignal(sig) {
+				s.Stop(sig)
+			} else {
+				s.Signal(sig)
+			}
+		}
+	}()
+
+	// Exit with the status of the process.
+	if result := s.Run(); result != nil {
+		return result.ExitStatus
+	}
+
+	return 0
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"github.com/nickbruun/coyote/errorhandlers"
+	"testing"
+	"time"
+)
+
+// Error handler recording whether errors were handled by an underlying
+// handler.
+type reportedHandler struct {
+	handler  errorhandlers.Handler
+	reported chan struct{}
+}
+
+func (h *reportedHandler) Handle(e *errorhandlers.Error) error {
+	defer func() {
+		select {
+		case h.reported <- struct{}{}:
+		default:
+		}
+	}()
+
+	return h.handler.Handle(e)
+}
+
+func TestRunProcessErrorHookClosingStdin(t *testing.T) {
+	// The hook closes its stdin without reading the error, which is larger
+	// than a pipe buffer, so writing it raises SIGPIPE in coyoterun.
+	hook, err := errorhandlers.NewExecErrorHandler("/bin/sh", []string{"-c", "exec 0<&-; sleep 0.2"}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create error hook: %s", err)
+	}
+
+	h := &reportedHandler{
+		handler:  hook,
+		reported: make(chan struct{}, 1),
+	}
+
+	// The process stalls after writing the lines included in the error, and
+	// must survive the error being reported.
+	exitStatus := runProcess([]string{"/bin/sh", "-c", `
+line=$(printf '%08192d' 0)
+for i in $(seq 10); do echo "$line"; done
+sleep 1
+`}, nil, coyote.SupervisorConfig{
+		ErrorHandler:   h,
+		OutputWatchdog: 200 * time.Millisecond,
+	})
+
+	select {
+	case <-h.reported:
+	default:
+		t.Fatalf("Expected the stall to be reported to the error hook")
+	}
+
+	if exitStatus != 0 {
+		t.Errorf("Expected the process to survive reporting the stall, but got exit status %d", exitStatus)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Procfile process type name pattern.
+var procfileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Procfile entry.
+type procfileEntry struct {
+	// Process type name.
+	name string
+
+	// Command, run through the shell.
+	command string
+}
+
+// Read a Procfile.
+//
+// Each non-empty line not starting with # declares a process type in the
+// form <name>: <command>.
+func readProcfile(path string) ([]procfileEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []procfileEntry
+	names := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		colonPos := strings.IndexByte(line, ':')
+		if colonPos == -1 {
+			return nil, fmt.Errorf("line %d: expected <name>: <command>", lineNo)
+		}
+
+		entry := procfileEntry{
+			name:    strings.TrimSpace(line[:colonPos]),
+			command: strings.TrimSpace(line[colonPos+1:]),
+		}
+
+		if !procfileNamePattern.MatchString(entry.name) {
+			return nil, fmt.Errorf("line %d: invalid process type name: %s", lineNo, entry.name)
+		}
+		if names[entry.name] {
+			return nil, fmt.Errorf("line %d: duplicate process type: %s", lineNo, entry.name)
+		}
+		if entry.command == "" {
+			return nil, fmt.Errorf("line %d: no command for process type %s", lineNo, entry.name)
+		}
+
+		names[entry.name] = true
+		entries = append(entries, entry)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no process types declared")
+	}
+
+	return entries, nil
+}
+
+// Split a flag value which may be prefixed by a process type name in the form
+// <name>:<value>.
+//
+// Returns an empty name if the value is not prefixed.
+func splitProcessFlagValue(value string) (name, rest string) {
+	if colonPos := strings.IndexByte(value, ':'); colonPos != -1 && procfileNamePattern.MatchString(value[:colonPos]) {
+		return value[:colonPos], value[colonPos+1:]
+	}
+
+	return "", value
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReadProcfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coyote-procfile")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeConfig(t, dir, "Procfile", `
+# Web server.
+web: bundle exec rails server -p $PORT
+
+  worker_1:   bundle exec sidekiq -q "a:b"
+	# Indented comment.
+clock-2:sleep 1
+`)
+
+	entries, err := readProcfile(path)
+	if err != nil {
+		t.Fatalf("Expected reading the Procfile to succeed, but got: %s", err)
+	}
+
+	expected := []procfileEntry{
+		{"web", "bundle exec rails server -p $PORT"},
+		{"worker_1", `bundle exec sidekiq -q "a:b"`},
+		{"clock-2", "sleep 1"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected reading the Procfile to result in %+v, but got %+v", expected, entries)
+	}
+
+	for _, tc := range []struct {
+		data     string
+		expected string
+	}{
+		{"web: a\nweb: b\n", "line 2: duplicate process type: web"},
+		{"web:\n", "line 1: no command for process type web"},
+		{"web:   \n", "line 1: no command for process type web"},
+		{"web a\n", "line 1: expected <name>: <command>"},
+		{": a\n", "line 1: invalid process type name: "},
+		{"web server: a\n", "line 1: invalid process type name: web server"},
+		{"\n# web: a\n\n", "no process types declared"},
+		{"", "no process types declared"},
+	} {
+		_, err := readProcfile(writeConfig(t, dir, "Procfile", tc.data))
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Expected reading %q to fail with %q, but got: %v", tc.data, tc.expected, err)
+		}
+	}
+
+	if _, err := readProcfile(dir + "/missing"); err == nil {
+		t.Errorf("Expected reading a missing Procfile to fail")
+	}
+}
+
+func TestSplitProcessFlagValue(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		name  string
+		rest  string
+	}{
+		{"always", "", "always"},
+		{"web:always", "web", "always"},
+		{"worker_1:10s", "worker_1", "10s"},
+		{"web:", "web", ""},
+		{":always", "", ":always"},
+		{"web server:always", "", "web server:always"},
+		{"web:a:b", "web", "a:b"},
+	} {
+		name, rest := splitProcessFlagValue(tc.value)
+		if name != tc.name || rest != tc.rest {
+			t.Errorf("Expected splitting %q to result in %q and %q, but got %q and %q", tc.value, tc.name, tc.rest, name, rest)
+		}
+	}
+}
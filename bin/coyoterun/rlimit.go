@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"strconv"
+	"strings"
+)
+
+// Parse a resource limit flag value of the form <soft>[:<hard>].
+//
+// Limits are either unlimited, or a count if the resource is counted, and a
+// size otherwise.
+func parseRlimit(flag, name string, resource int, counted bool, value string) (coyote.Rlimit, error) {
+	limit := coyote.Rlimit{
+		Resource: resource,
+		Name:     name,
+	}
+
+	parse := func(v string) (uint64, error) {
+		if v == "unlimited" {
+			return coyote.RlimitInfinity, nil
+		}
+
+		if counted {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return 0, FlagParseErrorf("invalid limit for %s: %s", flag, v)
+			}
+			return n, nil
+		}
+
+		return parseByteSizeFlag(flag, v)
+	}
+
+	soft, hard := value, ""
+	if colonPos := strings.IndexByte(value, ':'); colonPos != -1 {
+		soft, hard = value[:colonPos], value[colonPos+1:]
+	}
+
+	var err error
+	if limit.Soft, err = parse(soft); err != nil {
+		return limit, err
+	}
+
+	if hard != "" {
+		if limit.Hard, err = parse(hard); err != nil {
+			return limit, err
+		}
+		if limit.Hard < limit.Soft {
+			return limit, FlagParseErrorf("soft limit exceeds hard limit for %s: %s", flag, value)
+		}
+		limit.HardSet = true
+	}
+
+	return limit, nil
+}
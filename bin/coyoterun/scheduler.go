@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"github.com/nickbruun/coyote"
+	"github.com/nickbruun/coyote/output"
+	"math/rand"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Overlap policy.
+//
+// Decides what happens when a scheduled run is due while the previous run is
+// still in progress.
+type overlapPolicy int
+
+const (
+	// Skip the run.
+	overlapPolicySkip overlapPolicy = iota
+
+	// Start the run once the previous run has finished. At most one run is
+	// queued.
+	overlapPolicyQueue
+
+	// Stop the previous run and start the run once it has finished.
+	overlapPolicyKillPrevious
+)
+
+// Parse an overlap policy.
+func parseOverlapPolicy(value string) (overlapPolicy, error) {
+	switch value {
+	case "skip":
+		return overlapPolicySkip, nil
+	case "queue":
+		return overlapPolicyQueue, nil
+	case "kill-previous":
+		return overlapPolicyKillPrevious, nil
+	default:
+		return 0, FlagParseErrorf("invalid overlap policy: %s", value)
+	}
+}
+
+// Run schedule.
+//
+// Implemented by *cron.Schedule.
+type runSchedule interface {
+	// Next activation after a time.
+	Next(t time.Time) time.Time
+}
+
+// Scheduler.
+//
+// Runs a command on a cron schedule, keeping the outputs open between runs.
+type scheduler struct {
+	schedule runSchedule
+	jitter   time.Duration
+	overlap  overlapPolicy
+	args     []string
+	outputs  []output.Output
+	config   coyote.SupervisorConfig
+}
+
+// Wait for the next scheduled run.
+func (s *scheduler) nextTimer() *time.Timer {
+	now := time.Now()
+	delay := s.schedule.Next(now).Sub(now)
+
+	if s.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.jitter)))
+	}
+
+	return time.NewTimer(delay)
+}
+
+// Run a run to completion.
+//
+// Returns the exit status of the process if it failed, or 0 otherwise.
+func (s *scheduler) runOnce(sv *coyote.Supervisor) int {
+	startedAt := time.Now()
+	result := sv.Run()
+	duration := time.Since(startedAt)
+
+	if result == nil || !result.Failed {
+		sinkLine([]byte(fmt.Sprintf("Run finished in %s", duration)), s.outputs)
+		return 0
+	}
+
+	sinkLine([]byte(fmt.Sprintf("Run failed after %s", duration)), s.outputs)
+
+	return result.ExitStatus
+}
+
+// Run the command on the schedule.
+//
+// Runs until coyoterun is asked to stop, forwarding signals to the current
+// run. Returns the exit status of the last run if it failed, or 0 otherwise.
+func (s *scheduler) run() int {
+	sigs := make(chan os.Signal, 1)
+	notifySignals(sigs)
+	defer signal.Stop(sigs)
+
+	return s.runSignals(sigs)
+}
+
+// Run the command on the schedule until asked to stop by a signal.
+func (s *scheduler) runSignals(sigs <-chan os.Signal) int {
+	var current *coyote.Supervisor
+	finished := make(chan int, 1)
+	queued := false
+	stopping := false
+	exitStatus := 0
+
+	startRun := func() {
+		sinkLine([]byte("Starting run"), s.outputs)
+
+		current = coyote.NewSupervisor(s.args, s.outputs, s.config)
+
+		go func(sv *coyote.Supervisor) {
+			finished <- s.runOnce(sv)
+		}(current)
+	}
+
+	timer := s.nextTimer()
+	defer func() {
+		timer.Stop()
+	}()
+
+	for {
+		select {
+		case <-timer.C:
+			timer = s.nextTimer()
+
+			if current == nil {
+				startRun()
+				continue
+			}
+
+			switch s.overlap {
+			case overlapPolicySkip:
+				sinkLine([]byte("Skipping run: previous run still in progress"), s.outputs)
+
+			case overlapPolicyQueue:
+				if queued {
+					sinkLine([]byte("Skipping run: previous run still in progress and a run is already queued"), s.outputs)
+				} else {
+					sinkLine([]byte("Queueing run: previous run still in progress"), s.outputs)
+					queued = true
+				}
+
+			case overlapPolicyKillPrevious:
+				current.Terminate("superseded by the next scheduled run")
+				queued = true
+			}
+
+		case exitStatus = <-finished:
+			current = nil
+
+			if stopping {
+				return exitStatus
+			}
+
+			if queued {
+				queued = false
+				startRun()
+			}
+
+		case sig := <-sigs:
+			if !isForwardedSignal(sig) {
+				continue
+			}
+
+			if coyote.IsStopSignal(sig) {
+				stopping = true
+				timer.Stop()
+
+				if current == nil {
+					return exitStatus
+				}
+
+				current.Stop(sig)
+			} else if current != nil {
+				current.Signal(sig)
+			}
+		}
+	}
+}
+
+// New scheduler.
+//
+// Runs are never restarted, regardless of the restart policy of the
+// configuration.
+func newScheduler(schedule runSchedule, jitter time.Duration, overlap overlapPolicy, args []string, outputs []output.Output, config coyote.SupervisorConfig) *scheduler {
+	config.Restart = coyote.RestartNo
+
+	return &scheduler{
+		schedule: schedule,
+		jitter:   jitter,
+		overlap:  overlap,
+		args:     args,
+		outputs:  outputs,
+		config:   config,
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"github.com/nickbruun/coyote/output"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Schedule activating at a fixed interval.
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// Run a command on a schedule for a while and return the lines sunk.
+func runScheduled(t *testing.T, overlap overlapPolicy, args []string, interval, duration time.Duration) []string {
+	o := &recordingOutput{}
+	s := newScheduler(intervalSchedule(interval), 0, overlap, args, []output.Output{o}, coyote.SupervisorConfig{})
+
+	sigs := make(chan os.Signal, 1)
+	exited := make(chan struct{})
+
+	go func() {
+		s.runSignals(sigs)
+		close(exited)
+	}()
+
+	time.Sleep(duration)
+	sigs <- syscall.SIGTERM
+
+	select {
+	case <-exited:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected the scheduler to stop")
+	}
+
+	return o.lines()
+}
+
+// Count the lines starting with a prefix.
+func countLines(lines []string, prefix string) int {
+	count := 0
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	// Runs take far longer than the interval, so the next runs are due while
+	// the first is still in progress.
+	args := []string{"sleep", "10"}
+
+	lines := runScheduled(t, overlapPolicySkip, args, 100*time.Millisecond, 450*time.Millisecond)
+	if countLines(lines, "Starting run") != 1 || countLines(lines, "Skipping run: previous run still in progress") < 2 {
+		t.Errorf("Expected runs to be skipped while the first is in progress, but got %q", lines)
+	}
+
+	lines = runScheduled(t, overlapPolicyQueue, args, 100*time.Millisecond, 450*time.Millisecond)
+	if countLines(lines, "Starting run") != 1 || countLines(lines, "Queueing run: previous run still in progress") != 1 || countLines(lines, "Skipping run: previous run still in progress and a run is already queued") < 1 {
+		t.Errorf("Expected a single run to be queued while the first is in progress, but got %q", lines)
+	}
+
+	lines = runScheduled(t, overlapPolicyKillPrevious, args, 100*time.Millisecond, 450*time.Millisecond)
+	if countLines(lines, "Starting run") < 3 || countLines(lines, "Run failed after") < 2 || countLines(lines, "Skipping run") != 0 {
+		t.Errorf("Expected runs to be stopped by the next run, but got %q", lines)
+	}
+
+	// Queued runs are started once the previous run has finished.
+	lines = runScheduled(t, overlapPolicyQueue, []string{"sleep", "0.15"}, 100*time.Millisecond, 700*time.Millisecond)
+	if countLines(lines, "Queueing run") < 1 || countLines(lines, "Run finished in") < 2 {
+		t.Errorf("Expected queued runs to be started after the previous run, but got %q", lines)
+	}
+}
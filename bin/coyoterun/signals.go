@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"os"
+	"syscall"
+)
+
+// Parse a signal flag value.
+func parseSignal(value string) (syscall.Signal, error) {
+	sig, err := coyote.ParseSignal(value)
+	if err != nil {
+		return 0, FlagParseErrorf("%s", err)
+	}
+
+	return sig, nil
+}
+
+// Test if a signal received by coyoterun is forwarded to processes.
+//
+// SIGPIPE is caught but not forwarded, as it is raised by writes of coyoterun
+// itself, for example to error hooks which do not read their input.
+func isForwardedSignal(sig os.Signal) bool {
+	return sig != syscall.SIGPIPE
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"github.com/nickbruun/coyote"
+	"github.com/nickbruun/coyote/output"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Parse a restart policy.
+func parseRestartPolicy(value string) (coyote.RestartPolicy, error) {
+	switch value {
+	case "no":
+		return coyote.RestartNo, nil
+	case "on-failure":
+		return coyote.RestartOnFailure, nil
+	case "always":
+		return coyote.RestartAlways, nil
+	default:
+		return 0, FlagParseErrorf("invalid restart policy: %s", value)
+	}
+}
+
+// Procfile supervisor.
+//
+// Runs the process types of a Procfile concurrently, each under a supervisor
+// of its own.
+type procfileSupervisor struct {
+	supervisors []*coyote.Supervisor
+
+	// Stop all processes once any of them has exited for good.
+	stopAll bool
+
+	stopping bool
+	mutex    sync.Mutex
+}
+
+// Signal the running processes.
+func (s *procfileSupervisor) signal(sig os.Signal) {
+	for _, sv := range s.supervisors {
+		sv.Signal(sig)
+	}
+}
+
+// Stop all processes.
+//
+// Processes are not restarted once stopping.
+func (s *procfileSupervisor) stop(sig os.Signal) {
+	s.mutex.Lock()
+	s.stopping = true
+	s.mutex.Unlock()
+
+	for _, sv := range s.supervisors {
+		sv.Stop(sig)
+	}
+}
+
+// Test if stopping.
+func (s *procfileSupervisor) isStopping() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.stopping
+}
+
+// Run the processes.
+//
+// Forwards signals to the processes while they run. Returns the exit status
+// of the first process to fail, or 0 if none failed.
+func (s *procfileSupervisor) runAll() int {
+	// Forward signals to the processes, stopping all of them on stop signals.
+	sigs := make(chan os.Signal, 1)
+	notifySignals(sigs)
+	defer signal.Stop(sigs)
+
+	go func() {
+		for sig := range sigs {
+			if !isForwardedSignal(sig) {
+				continue
+			}
+
+			if coyote.IsStopSignal(sig) {
+				s.stop(sig)
+			} else {
+				s.signal(sig)
+			}
+		}
+	}()
+
+	// Run the processes.
+	results := make(chan *coyote.ExitResult, len(s.supervisors))
+
+	for _, sv := range s.supervisors {
+		go func(sv *coyote.Supervisor) {
+			results <- sv.Run()
+		}(sv)
+	}
+
+	exitStatus := 0
+	failed := false
+
+	for range s.supervisors {
+		result := <-results
+
+		if result != nil && result.Failed && !failed {
+			failed = true
+			exitStatus = result.ExitStatus
+			if exitStatus == 0 {
+				exitStatus = 1
+			}
+		}
+
+		if s.stopAll && !s.isStopping() {
+			s.stop(syscall.SIGTERM)
+		}
+	}
+
+	return exitStatus
+}
+
+// New supervisor for the process types of a Procfile.
+//
+// Processes share the outputs, to which their lines are sunk labeled by the
+// process type name. Each process type is run with its configuration.
+func newProcfileSupervisor(entries []procfileEntry, outputs []output.Output, configs map[string]coyote.SupervisorConfig, stopAll bool) *procfileSupervisor {
+	s := &procfileSupervisor{
+		stopAll: stopAll,
+	}
+
+	width := 0
+	for _, e := range entries {
+		if len(e.name) > width {
+			width = len(e.name)
+		}
+	}
+
+	for i, e := range entries {
+		config := configs[e.name]
+		config.Name = e.name
+
+		s.supervisors = append(s.supervisors, coyote.NewSupervisor(
+			[]string{"/bin/sh", "-c", e.command},
+			labelOutputs(outputs, e.name, width, i),
+			config,
+		))
+	}
+
+	return s
+}
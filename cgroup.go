@@ -0,0 +1,22 @@
+package coyote
+
+// Period of the CPU limit of cgroups, in microseconds.
+const CgroupCpuPeriod = 100000
+
+// Cgroup configuration.
+type CgroupConfig struct {
+	// Path of the parent cgroup, relative to the cgroup v2 hierarchy root.
+	// Empty uses the cgroup of the current process.
+	Parent string
+
+	// Memory limit in bytes. Zero is unlimited.
+	MemoryMax uint64
+
+	// CPU limit in number of CPUs. Zero is unlimited.
+	CpuMax float64
+}
+
+// Test if processes should be placed in a cgroup.
+func (c *CgroupConfig) Enabled() bool {
+	return c.MemoryMax > 0 || c.CpuMax > 0
+}
@@ -0,0 +1,232 @@
+//go:build linux
+// +build linux
+
+package coyote
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Number of cgroups created, used for naming them.
+var cgroupCount int64
+
+// Time waited for a cgroup to become empty before removing it.
+const cgroupRemoveTimeout = 5 * time.Second
+
+// Cgroup.
+//
+// cgroup v2 sub-tree in which a process is placed.
+type cgroup struct {
+	path string
+	dir  *os.File
+}
+
+// Find the mount point of the cgroup v2 hierarchy.
+func cgroupMountPoint() (string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The filesystem type follows the separator after the optional
+		// fields.
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && fields[i+1] == "cgroup2" && len(fields) > 4 {
+				return fields[4], nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no cgroup v2 hierarchy mounted")
+}
+
+// Find the cgroup v2 path of the current process.
+func ownCgroupPath() (string, error) {
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			return line[3:], nil
+		}
+	}
+
+	return "", fmt.Errorf("not in a cgroup v2 hierarchy")
+}
+
+// Write a cgroup control file.
+func writeCgroupFile(dir, name, value string) error {
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %s", name, err)
+	}
+
+	return nil
+}
+
+// Enable controllers for the children of a cgroup.
+//
+// cgroups with controllers enabled for their children cannot contain
+// processes themselves. If the current process is in the cgroup, it is moved
+// to a leaf cgroup of its own first.
+func enableCgroupControllers(dir string, controllers []string, own bool) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("failed to read available controllers: %s", err)
+	}
+
+	available := strings.Fields(string(data))
+	var enable []string
+
+	for _, c := range controllers {
+		found := false
+		for _, a := range available {
+			found = found || a == c
+		}
+
+		if !found {
+			return fmt.Errorf("%s controller not available in %s", c, dir)
+		}
+
+		enable = append(enable, "+"+c)
+	}
+
+	err = writeCgroupFile(dir, "cgroup.subtree_control", strings.Join(enable, " "))
+	if err == nil || !own {
+		return err
+	}
+
+	leaf := filepath.Join(dir, "coyote")
+	if err := os.Mkdir(leaf, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create leaf cgroup: %s", err)
+	}
+
+	if err := writeCgroupFile(leaf, "cgroup.procs", strconv.Itoa(os.Getpid())); err != nil {
+		return err
+	}
+
+	return writeCgroupFile(dir, "cgroup.subtree_control", strings.Join(enable, " "))
+}
+
+// Create a cgroup for a process.
+func newCgroup(config CgroupConfig) (*cgroup, error) {
+	root, err := cgroupMountPoint()
+	if err != nil {
+		return nil, err
+	}
+
+	parent := config.Parent
+	own := false
+	if parent == "" {
+		if parent, err = ownCgroupPath(); err != nil {
+			return nil, err
+		}
+		own = true
+	}
+
+	parentDir := filepath.Join(root, parent)
+
+	var controllers []string
+	if config.MemoryMax > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if config.CpuMax > 0 {
+		controllers = append(controllers, "cpu")
+	}
+
+	if err = enableCgroupControllers(parentDir, controllers, own); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(parentDir, fmt.Sprintf("coyote-%d-%d", os.Getpid(), atomic.AddInt64(&cgroupCount, 1)))
+	if err = os.Mkdir(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %s", err)
+	}
+
+	cg := &cgroup{path: path}
+
+	if config.MemoryMax > 0 {
+		if err = writeCgroupFile(path, "memory.max", strconv.FormatUint(config.MemoryMax, 10)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+
+	if config.CpuMax > 0 {
+		if err = writeCgroupFile(path, "cpu.max", fmt.Sprintf("%d %d", int64(config.CpuMax*CgroupCpuPeriod), CgroupCpuPeriod)); err != nil {
+			cg.remove()
+			return nil, err
+		}
+	}
+
+	if cg.dir, err = os.Open(path); err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("failed to open cgroup: %s", err)
+	}
+
+	return cg, nil
+}
+
+// Place a process started with the attributes in the cgroup.
+func (cg *cgroup) apply(attr *syscall.SysProcAttr) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(cg.dir.Fd())
+}
+
+// Test if any process in the cgroup has been killed by the OOM killer.
+func (cg *cgroup) oomKilled() bool {
+	data, err := ioutil.ReadFile(filepath.Join(cg.path, "memory.events"))
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			n, _ := strconv.Atoi(fields[1])
+			return n > 0
+		}
+	}
+
+	return false
+}
+
+// Remove the cgroup.
+//
+// Waits for processes in the cgroup to exit, as the cgroup can only be
+// removed once empty.
+func (cg *cgroup) remove() {
+	if cg.dir != nil {
+		cg.dir.Close()
+	}
+
+	deadline := time.Now().Add(cgroupRemoveTimeout)
+
+	for {
+		err := syscall.Rmdir(cg.path)
+		if err == nil || err == syscall.ENOENT {
+			return
+		}
+
+		if err != syscall.EBUSY || time.Now().After(deadline) {
+			fmt.Fprintf(os.Stderr, "Failed to remove cgroup %s: %s\n", cg.path, err)
+			return
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !linux
+// +build !linux
+
+package coyote
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Cgroup.
+type cgroup struct{}
+
+// Create a cgroup for a process.
+//
+// Only supported on Linux.
+func newCgroup(config CgroupConfig) (*cgroup, error) {
+	return nil, fmt.Errorf("cgroups are only supported on Linux")
+}
+
+// Place a process started with the attributes in the cgroup.
+func (cg *cgroup) apply(attr *syscall.SysProcAttr) {}
+
+// Test if any process in the cgroup has been killed by the OOM killer.
+func (cg *cgroup) oomKilled() bool {
+	return false
+}
+
+// Remove the cgroup.
+func (cg *cgroup) remove() {}
@@ -0,0 +1,2 @@
+// Package cron provides parsing and evaluation of cron schedules.
+package cron
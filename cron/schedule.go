@@ -0,0 +1,237 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule field.
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+// Schedule fields in order.
+var fields = []field{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{"day of week", 0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// Schedule macros.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Maximum time searched for the next activation.
+//
+// Schedules which never activate, such as on the 31st of February, are
+// detected by searching past any leap year.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule.
+type Schedule struct {
+	spec string
+
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+
+	// Whether the day of month and day of week fields are restricted.
+	domRestricted bool
+	dowRestricted bool
+}
+
+// Parse a value of a field.
+func (f *field) parseValue(value string) (int, error) {
+	if n, ok := f.names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("invalid %s: %s", f.name, value)
+	}
+
+	return n, nil
+}
+
+// Parse a field into a bit set of the values it matches.
+//
+// Returns whether the field is restricted, meaning that it does not start
+// with a wildcard, like in Vixie cron. Stepped wildcards such as */2 are
+// therefore not restricted.
+func (f *field) parse(spec string) (bits uint64, restricted bool, err error) {
+	restricted = !strings.HasPrefix(spec, "*")
+
+	for _, part := range strings.Split(spec, ",") {
+		rangeSpec := part
+		step := 1
+
+		if slashPos := strings.IndexByte(part, '/'); slashPos != -1 {
+			rangeSpec = part[:slashPos]
+
+			if step, err = strconv.Atoi(part[slashPos+1:]); err != nil || step < 1 {
+				return 0, false, fmt.Errorf("invalid %s step: %s", f.name, part[slashPos+1:])
+			}
+		}
+
+		var from, to int
+
+		if rangeSpec == "*" {
+			from, to = f.min, f.max
+		} else if dashPos := strings.IndexByte(rangeSpec, '-'); dashPos != -1 {
+			if from, err = f.parseValue(rangeSpec[:dashPos]); err != nil {
+				return 0, false, err
+			}
+			if to, err = f.parseValue(rangeSpec[dashPos+1:]); err != nil {
+				return 0, false, err
+			}
+			if to < from {
+				return 0, false, fmt.Errorf("invalid %s range: %s", f.name, rangeSpec)
+			}
+		} else {
+			if from, err = f.parseValue(rangeSpec); err != nil {
+				return 0, false, err
+			}
+
+			// A single value with a step starts a range.
+			to = from
+			if step > 1 {
+				to = f.max
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, restricted, nil
+}
+
+// Parse a schedule.
+//
+// The schedule is specified in the standard five field format of minute,
+// hour, day of month, month and day of week. Fields may be wildcards, values,
+// ranges and comma-separated lists, each optionally with a step. Months and
+// days of week may be specified by their three letter English names, and
+// Sunday is both 0 and 7. If both the day of month and day of week are
+// restricted, either must match, while otherwise both must match. Like in
+// Vixie cron, fields starting with a wildcard, including stepped wildcards
+// such as */2, are not restricted. The macros @yearly, @annually, @monthly,
+// @weekly, @daily, @midnight and @hourly are supported as well.
+func Parse(spec string) (*Schedule, error) {
+	expanded := strings.TrimSpace(spec)
+	if m, ok := macros[strings.ToLower(expanded)]; ok {
+		expanded = m
+	}
+
+	parts := strings.Fields(expanded)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("expected %d fields, but got %d", len(fields), len(parts))
+	}
+
+	bits := make([]uint64, len(fields))
+	restricted := make([]bool, len(fields))
+
+	for i := range fields {
+		var err error
+		if bits[i], restricted[i], err = fields[i].parse(parts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sunday may be specified as 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	s := &Schedule{
+		spec:          spec,
+		minutes:       bits[0],
+		hours:         bits[1],
+		daysOfMonth:   bits[2],
+		months:        bits[3],
+		daysOfWeek:    bits[4],
+		domRestricted: restricted[2],
+		dowRestricted: restricted[4],
+	}
+
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule never activates")
+	}
+
+	return s, nil
+}
+
+// Test if the schedule matches the day of a time.
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom := s.daysOfMonth&(1<<uint(t.Day())) != 0
+	dow := s.daysOfWeek&(1<<uint(t.Weekday())) != 0
+
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+// Next activation after a time.
+//
+// Activations are evaluated in the location of the time. Returns the zero
+// time if the schedule never activates.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) String() string {
+	return s.spec
+}
@@ -0,0 +1,102 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"0 0 31 2 *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Expected parsing %q to fail", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	from := time.Date(2021, 3, 15, 10, 7, 30, 0, time.UTC) // Monday.
+
+	for _, tc := range []struct {
+		spec     string
+		expected []time.Time
+	}{
+		{"* * * * *", []time.Time{
+			time.Date(2021, 3, 15, 10, 8, 0, 0, time.UTC),
+			time.Date(2021, 3, 15, 10, 9, 0, 0, time.UTC),
+		}},
+		{"*/5 * * * *", []time.Time{
+			time.Date(2021, 3, 15, 10, 10, 0, 0, time.UTC),
+			time.Date(2021, 3, 15, 10, 15, 0, 0, time.UTC),
+		}},
+		{"0,30 9-17 * * mon-fri", []time.Time{
+			time.Date(2021, 3, 15, 10, 30, 0, 0, time.UTC),
+			time.Date(2021, 3, 15, 11, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 * * 7", []time.Time{
+			time.Date(2021, 3, 21, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 28, 0, 0, 0, 0, time.UTC),
+		}},
+		{"@monthly", []time.Time{
+			time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"0 12 29 feb *", []time.Time{
+			time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+			time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC),
+		}},
+		// Either the day of month or the day of week must match when both
+		// are restricted.
+		{"0 0 1 * fri", []time.Time{
+			time.Date(2021, 3, 19, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 26, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 4, 2, 0, 0, 0, 0, time.UTC),
+		}},
+		// Stepped wildcards are not restricted, so both must match.
+		{"0 0 */2 * 1", []time.Time{
+			time.Date(2021, 3, 29, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 4, 5, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 4, 19, 0, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 1-31/2 * 1", []time.Time{
+			time.Date(2021, 3, 17, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 19, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 21, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 22, 0, 0, 0, 0, time.UTC),
+		}},
+		{"15/20 3 * * *", []time.Time{
+			time.Date(2021, 3, 16, 3, 15, 0, 0, time.UTC),
+			time.Date(2021, 3, 16, 3, 35, 0, 0, time.UTC),
+			time.Date(2021, 3, 16, 3, 55, 0, 0, time.UTC),
+			time.Date(2021, 3, 17, 3, 15, 0, 0, time.UTC),
+		}},
+	} {
+		s, err := Parse(tc.spec)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", tc.spec, err)
+			continue
+		}
+
+		next := from
+		for _, expected := range tc.expected {
+			next = s.Next(next)
+			if !next.Equal(expected) {
+				t.Errorf("Expected %q to activate at %s, but got %s", tc.spec, expected, next)
+				break
+			}
+		}
+	}
+}
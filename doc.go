@@ -0,0 +1,18 @@
+// Package coyote supervises processes, draining their output to outputs and
+// reporting failures to error handlers.
+//
+// A Supervisor runs a command in its own process group, restarting it
+// according to its restart policy, and returns an ExitResult describing how
+// the last run ended:
+//
+//	s := coyote.NewSupervisor([]string{"myapp"}, outputs, coyote.SupervisorConfig{
+//		ErrorHandler: handler,
+//		Restart:      coyote.RestartOnFailure,
+//		StopTimeout:  10 * time.Second,
+//	})
+//
+//	result := s.Run()
+//
+// Signals are not forwarded to the process automatically. Use Signal to
+// forward them, and Stop or Terminate to stop the process for good.
+package coyote
@@ -0,0 +1,67 @@
+package coyote
+
+import (
+	"bufio"
+	"github.com/nickbruun/coyote/output"
+	"io"
+	"time"
+)
+
+// Sink a line.
+func sinkLine(l []byte, outputs []output.Output) {
+	for _, o := range outputs {
+		o.Sink(l)
+	}
+}
+
+// Drain and sink lines from a reader.
+//
+// Reads until the reader is exhausted or fails. If a filter is provided,
+// lines are passed through it before being sunk.
+func Drain(r io.Reader, outputs []output.Output, filter func([]byte) []byte) {
+	drain(r, outputs, filter, nil, "", nil)
+}
+
+// Drain and sink lines from a stream of a command as records.
+//
+// If a parser is provided, records are parsed by it before being sunk.
+func drain(r io.Reader, outputs []output.Output, filter func([]byte) []byte, parser *output.JsonParser, stream string, cmd []string) {
+	br := bufio.NewReader(r)
+
+	for {
+		// Read as many lines as we can.
+		line, err := br.ReadBytes('\n')
+
+		if len(line) > 0 {
+			// Strip any [CR]LF from the line.
+			line = line[:len(line)-1]
+			if len(line) > 0 && line[len(line)-1] == '\r' {
+				line = line[:len(line)-1]
+			}
+
+			// Sink the output.
+			if filter != nil {
+				line = filter(line)
+			}
+
+			rec := output.Record{
+				Time:   time.Now(),
+				Stream: stream,
+				Cmd:    cmd,
+				Line:   line,
+			}
+
+			if parser != nil {
+				parser.Parse(&rec)
+			}
+
+			for _, o := range outputs {
+				output.SinkRecord(o, rec)
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package coyote
+
+import (
+	"fmt"
+	"github.com/nickbruun/coyote/errorhandlers"
+	"os"
+	"strings"
+	"time"
+)
+
+// Emit error.
+//
+// The environment is that of the process, or nil if the process inherits the
+// environment of the current process. The exit details are included if the
+// process has exited.
+func emitError(cmd []string, env []string, err error, result *ExitResult, tail *outputTail, errorHandler errorhandlers.Handler) {
+	// Construct the error.
+	timestamp := time.Now().UTC()
+	hostname, _ := os.Hostname()
+
+	if env == nil {
+		env = os.Environ()
+	}
+
+	environ := make(map[string]string, len(env))
+	for _, env := range env {
+		var k, v string
+
+		equalPos := strings.IndexByte(env, '=')
+		if equalPos == -1 {
+			k = env
+		} else {
+			k = env[:equalPos]
+			v = env[equalPos+1:]
+		}
+
+		environ[k] = v
+	}
+
+	errMsg := &errorhandlers.Error{
+		Cmd:        cmd,
+		Desc:       err.Error(),
+		ExitStatus: -1,
+		Output:     tail.Lines(),
+		Hostname:   hostname,
+		Environ:    environ,
+		Timestamp:  timestamp,
+	}
+
+	if result != nil {
+		errMsg.ExitStatus = result.ExitStatus
+		errMsg.Duration = result.Duration
+		errMsg.CoreDumped = result.CoreDumped
+
+		if result.Signal != 0 {
+			errMsg.Signal = SignalName(result.Signal)
+		}
+
+		if result.Rusage != nil {
+			errMsg.MaxRss = maxRssBytes(result.Rusage)
+			errMsg.UserTime = time.Duration(result.Rusage.Utime.Nano())
+			errMsg.SystemTime = time.Duration(result.Rusage.Stime.Nano())
+		}
+	}
+
+	// Emit the error.
+	if err := errorHandler.Handle(errMsg); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send error message: %s\n", err)
+	}
+}
@@ -0,0 +1,285 @@
+package errorhandlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Deduplication window.
+//
+// Only the timing of occurrences is persisted, as errors may contain output
+// and environment variables which should not be written to disk.
+type deduplicationWindow struct {
+	// Start of the window.
+	Start time.Time `json:"start"`
+
+	// Number of occurrences within the window.
+	Count int `json:"count"`
+
+	// Last occurrence, if any since the state was loaded.
+	err *Error
+
+	timer *time.Timer
+}
+
+// Deduplicating error handler.
+//
+// Error handler which forwards the first occurrence of an error and suppresses
+// identical errors within a window. When the window closes, a summary of the
+// suppressed occurrences is forwarded. Errors are identical if they have the
+// same fingerprint.
+type DeduplicatingHandler struct {
+	handler   Handler
+	window    time.Duration
+	statePath string
+	windows   map[string]*deduplicationWindow
+	mutex     sync.Mutex
+	closed    bool
+}
+
+// Fingerprint an error.
+//
+// The fingerprint is based on the command, description, exit status and
+// signal. Output is not included, as it usually contains timestamps or process
+// IDs which differ between occurrences of the same error.
+func Fingerprint(err *Error) string {
+	h := sha256.New()
+
+	write := func(s string) {
+		h.Write([]byte(strconv.Itoa(len(s))))
+		h.Write([]byte{':'})
+		h.Write([]byte(s))
+	}
+
+	for _, p := range err.Cmd {
+		write(p)
+	}
+	write(err.Desc)
+	write(strconv.Itoa(err.ExitStatus))
+	write(err.Signal)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Summarize a window.
+//
+// Returns nil if no occurrences were suppressed. If the error has not
+// occurred since the state was loaded, only its fingerprint is known.
+func (h *DeduplicatingHandler) summarize(fingerprint string, w *deduplicationWindow, now time.Time) *Error {
+	if w.Count < 2 {
+		return nil
+	}
+
+	occurrences := fmt.Sprintf("occurred %d times in %s", w.Count, now.Sub(w.Start).Round(time.Second))
+
+	var summary Error
+	if w.err != nil {
+		summary = *w.err
+		summary.Desc = fmt.Sprintf("%s (%s)", w.err.Desc, occurrences)
+	} else {
+		summary.Desc = fmt.Sprintf("error with fingerprint %s %s", fingerprint, occurrences)
+		summary.ExitStatus = -1
+		summary.Hostname, _ = os.Hostname()
+	}
+
+	summary.Timestamp = now.UTC()
+	return &summary
+}
+
+// Save the state.
+//
+// Must be called with the mutex held.
+func (h *DeduplicatingHandler) save() error {
+	if h.statePath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(h.windows)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(h.statePath), "."+filepath.Base(h.statePath))
+	if err = ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, h.statePath)
+}
+
+// Close a window.
+func (h *DeduplicatingHandler) closeWindow(fingerprint string, w *deduplicationWindow) {
+	h.mutex.Lock()
+	if h.closed || h.windows[fingerprint] != w {
+		h.mutex.Unlock()
+		return
+	}
+
+	delete(h.windows, fingerprint)
+	summary := h.summarize(fingerprint, w, time.Now())
+
+	if err := h.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save error deduplication state: %s\n", err)
+	}
+	h.mutex.Unlock()
+
+	if summary != nil {
+		if err := h.handler.Handle(summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to send error message: %s\n", err)
+		}
+	}
+}
+
+// Schedule closing a window.
+//
+// Must be called with the mutex held.
+func (h *DeduplicatingHandler) scheduleWindow(fingerprint string, w *deduplicationWindow) {
+	w.timer = time.AfterFunc(h.window-time.Since(w.Start), func() {
+		h.closeWindow(fingerprint, w)
+	})
+}
+
+func (h *DeduplicatingHandler) Handle(err *Error) error {
+	fingerprint := Fingerprint(err)
+
+	h.mutex.Lock()
+
+	if w, ok := h.windows[fingerprint]; ok {
+		w.Count++
+		w.err = err
+		saveErr := h.save()
+		h.mutex.Unlock()
+
+		return saveErr
+	}
+
+	w := &deduplicationWindow{
+		Start: time.Now(),
+		Count: 1,
+		err:   err,
+	}
+	h.windows[fingerprint] = w
+	h.scheduleWindow(fingerprint, w)
+	saveErr := h.save()
+	h.mutex.Unlock()
+
+	if saveErr != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save error deduplication state: %s\n", saveErr)
+	}
+
+	return h.handler.Handle(err)
+}
+
+// Close the handler.
+//
+// If a state path is configured, open windows are saved for the next handler
+// using the same state path. Otherwise, summaries of open windows are
+// forwarded immediately.
+func (h *DeduplicatingHandler) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.closed = true
+
+	for _, w := range h.windows {
+		w.timer.Stop()
+	}
+
+	if h.statePath != "" {
+		return h.save()
+	}
+
+	now := time.Now()
+	for fingerprint, w := range h.windows {
+		if summary := h.summarize(fingerprint, w, now); summary != nil {
+			if err := h.handler.Handle(summary); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// New deduplicating error handler.
+//
+// If the state path is not empty, the windows are persisted in a file at the
+// path, so deduplication works across multiple runs. Only the fingerprints of
+// errors and the timing of their occurrences are persisted. Summaries of
+// windows which closed since the state was last saved are forwarded
+// immediately.
+func NewDeduplicatingHandler(handler Handler, window time.Duration, statePath string) (*DeduplicatingHandler, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+
+	h := &DeduplicatingHandler{
+		handler:   handler,
+		window:    window,
+		statePath: statePath,
+		windows:   make(map[string]*deduplicationWindow),
+	}
+
+	if statePath == "" {
+		return h, nil
+	}
+
+	// Load the state.
+	data, err := ioutil.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read deduplication state: %s", err)
+	}
+
+	windows := make(map[string]*deduplicationWindow)
+	if err = json.Unmarshal(data, &windows); err != nil {
+		return nil, fmt.Errorf("invalid deduplication state in %s: %s", statePath, err)
+	}
+
+	// Summarize closed windows and schedule the rest.
+	now := time.Now()
+	var summaries []*Error
+
+	for fingerprint, w := range windows {
+		if w == nil {
+			continue
+		}
+
+		if now.Sub(w.Start) >= window {
+			if summary := h.summarize(fingerprint, w, w.Start.Add(window)); summary != nil {
+				summaries = append(summaries, summary)
+			}
+			continue
+		}
+
+		h.windows[fingerprint] = w
+		h.scheduleWindow(fingerprint, w)
+	}
+
+	if len(summaries) > 0 {
+		h.mutex.Lock()
+		err = h.save()
+		h.mutex.Unlock()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to save deduplication state: %s", err)
+		}
+
+		for _, summary := range summaries {
+			if err = handler.Handle(summary); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to send error message: %s\n", err)
+			}
+		}
+	}
+
+	return h, nil
+}
@@ -0,0 +1,159 @@
+package errorhandlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	err := &Error{
+		Cmd:        []string{"myapp", "-v"},
+		Desc:       "process exited with status 1",
+		ExitStatus: 1,
+		Output:     []string{"2016-01-02 03:04:05 [123] starting", "2016-01-02 03:04:06 [123] failed"},
+	}
+
+	same := *err
+	same.Output = []string{"2016-01-02 03:05:05 [456] starting", "2016-01-02 03:05:06 [456] failed"}
+	same.Environ = map[string]string{"A": "b"}
+	same.Timestamp = time.Now()
+
+	if Fingerprint(err) != Fingerprint(&same) {
+		t.Errorf("Expected errors differing only in output to have the same fingerprint")
+	}
+
+	for _, modify := range []func(e *Error){
+		func(e *Error) { e.Cmd = []string{"myapp -v"} },
+		func(e *Error) { e.Desc = "process exited with status 2" },
+		func(e *Error) { e.ExitStatus = 2 },
+		func(e *Error) { e.Signal = "SIGKILL" },
+	} {
+		other := *err
+		modify(&other)
+
+		if Fingerprint(err) == Fingerprint(&other) {
+			t.Errorf("Expected %+v to have a different fingerprint than %+v", other, *err)
+		}
+	}
+}
+
+// Wait for a test handler to have handled a number of errors.
+func waitForHandled(t *testing.T, h *testHandler, count int) []*Error {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		h.mutex.Lock()
+		handled := h.handled
+		h.mutex.Unlock()
+
+		if len(handled) >= count {
+			return handled
+		}
+	}
+
+	t.Fatalf("Expected %d error(s) to be handled in time", count)
+	return nil
+}
+
+func TestDeduplicatingHandlerWindow(t *testing.T) {
+	h := &testHandler{}
+	d, err := NewDeduplicatingHandler(h, 100*time.Millisecond, "")
+	if err != nil {
+		t.Fatalf("Failed to create deduplicating handler: %s", err)
+	}
+	defer d.Close()
+
+	// Occurrences of the same error usually differ in output.
+	for i := 0; i < 3; i++ {
+		d.Handle(&Error{
+			Desc:   "test",
+			Output: []string{fmt.Sprintf("%s [%d] failed", time.Now().Format(time.RFC3339Nano), 100+i)},
+		})
+	}
+	d.Handle(&Error{Desc: "other"})
+
+	handled := waitForHandled(t, h, 2)
+	if handled[0].Desc != "test" || handled[1].Desc != "other" {
+		t.Errorf("Expected only the first occurrences to be handled, but got %+v", handled)
+	}
+
+	handled = waitForHandled(t, h, 3)
+	if len(handled) != 3 || !strings.HasPrefix(handled[2].Desc, "test (occurred 3 times in ") {
+		t.Errorf("Expected a summary of the suppressed occurrences, but got %+v", handled)
+	}
+
+	d.Handle(&Error{Desc: "test"})
+	if handled = waitForHandled(t, h, 4); handled[3].Desc != "test" {
+		t.Errorf("Expected the error to be handled again after the window closed, but got %+v", handled)
+	}
+}
+
+func TestDeduplicatingHandlerState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coyote-dedup")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	statePath := filepath.Join(dir, "state.json")
+	e := &Error{
+		Desc:    "test",
+		Output:  []string{"secret output"},
+		Environ: map[string]string{"TOKEN": "secret token"},
+	}
+
+	// Occurrences in the first run.
+	h := &testHandler{}
+	d, err := NewDeduplicatingHandler(h, time.Hour, statePath)
+	if err != nil {
+		t.Fatalf("Failed to create deduplicating handler: %s", err)
+	}
+
+	d.Handle(e)
+	d.Handle(e)
+
+	if err = d.Close(); err != nil {
+		t.Fatalf("Expected closing to succeed, but got: %s", err)
+	}
+	if len(h.handled) != 1 {
+		t.Errorf("Expected error to be handled once, but was handled %d time(s)", len(h.handled))
+	}
+
+	data, err := ioutil.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("Failed to read state: %s", err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "test") {
+		t.Errorf("Expected state to contain only fingerprints and timing, but got: %s", data)
+	}
+
+	// The window continues in the next run.
+	h = &testHandler{}
+	if d, err = NewDeduplicatingHandler(h, time.Hour, statePath); err != nil {
+		t.Fatalf("Failed to reload deduplicating handler: %s", err)
+	}
+
+	d.Handle(e)
+
+	if err = d.Close(); err != nil {
+		t.Fatalf("Expected closing to succeed, but got: %s", err)
+	}
+	if len(h.handled) != 0 {
+		t.Errorf("Expected error to be suppressed across runs, but got %+v", h.handled)
+	}
+
+	// The window has closed by the next run.
+	h = &testHandler{}
+	if d, err = NewDeduplicatingHandler(h, time.Nanosecond, statePath); err != nil {
+		t.Fatalf("Failed to reload deduplicating handler: %s", err)
+	}
+	defer d.Close()
+
+	expected := "error with fingerprint " + Fingerprint(e) + " occurred 3 times in 0s"
+	if len(h.handled) != 1 || h.handled[0].Desc != expected {
+		t.Errorf("Expected a summary naming the fingerprint, but got %+v", h.handled)
+	}
+}
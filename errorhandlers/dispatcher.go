@@ -0,0 +1,459 @@
+package errorhandlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Dispatcher configuration.
+type DispatcherConfig struct {
+	// Timeout of a single delivery attempt.
+	//
+	// Only applies to handlers which can be cancelled. Defaults to 30
+	// seconds.
+	Timeout time.Duration
+
+	// Number of times a failed delivery is retried.
+	Retries int
+
+	// Delay before the first retry.
+	//
+	// The delay is doubled for every subsequent retry. Defaults to 1 second.
+	Backoff time.Duration
+
+	// Maximum time spent waiting for deliveries to finish when closing.
+	//
+	// Defaults to 30 seconds.
+	Deadline time.Duration
+
+	// Spool directory.
+	//
+	// If not empty, errors that could not be delivered before closing are
+	// written to the directory and delivered by the next dispatcher using the
+	// same directory.
+	SpoolDir string
+}
+
+// Spooled error.
+type spooledError struct {
+	Handler string `json:"handler"`
+	Error   *Error `json:"error"`
+}
+
+// Suffix of spooled errors claimed for delivery.
+//
+// Claimed errors are renamed to <name>.<pid>.claimed by the process
+// delivering them.
+const claimedSuffix = ".claimed"
+
+// Delivery of an error to a handler.
+type delivery struct {
+	handler Handler
+	name    string
+	err     *Error
+
+	// Path of the spooled error, if any, removed once delivered.
+	path string
+}
+
+// Dispatcher.
+//
+// Error handler which delivers errors to a set of handlers concurrently and in
+// the background. Failed delivery attempts are retried with exponential
+// backoff. Attempts of handlers which can be cancelled are aborted and
+// considered failed once they exceed the timeout. Attempts of other handlers
+// are waited for regardless, as they may still deliver the error, so an
+// error is never delivered twice by retrying a running attempt.
+type Dispatcher struct {
+	handlers []Handler
+	names    []string
+	config   DispatcherConfig
+	wg       sync.WaitGroup
+	mutex    sync.Mutex
+	pending  map[*delivery]struct{}
+	closed   bool
+	seq      int
+}
+
+// Handler names.
+//
+// Used for identifying the handler of spooled errors across runs. Handlers
+// are named by their description, or their type if they have none, with the
+// index of the handler added to names already taken by earlier handlers.
+func handlerNames(handlers []Handler) []string {
+	names := make([]string, len(handlers))
+	taken := make(map[string]bool, len(handlers))
+
+	for i, h := range handlers {
+		name := fmt.Sprintf("%T", h)
+		if s, ok := h.(fmt.Stringer); ok {
+			name = s.String()
+		}
+
+		if taken[name] {
+			name = fmt.Sprintf("%s #%d", name, i)
+		}
+
+		names[i] = name
+		taken[name] = true
+	}
+
+	return names
+}
+
+// Attempt delivery.
+//
+// Handlers which can be cancelled are cancelled once the timeout is
+// exceeded.
+func (d *Dispatcher) attempt(dl *delivery) error {
+	ch, ok := dl.handler.(ContextHandler)
+	if !ok {
+		return dl.handler.Handle(dl.err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+
+	err := ch.HandleContext(ctx, dl.err)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", d.config.Timeout)
+	}
+
+	return err
+}
+
+// Deliver an error, retrying if necessary.
+func (d *Dispatcher) deliver(dl *delivery) {
+	defer d.wg.Done()
+
+	backoff := d.config.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err := d.attempt(dl)
+
+		if err == nil {
+			// The error may have been spooled by closing while the attempt
+			// was running.
+			d.mutex.Lock()
+			delete(d.pending, dl)
+			path := dl.path
+			d.mutex.Unlock()
+
+			if path != "" {
+				os.Remove(path)
+			}
+
+			return
+		}
+
+		if attempt >= d.config.Retries {
+			fmt.Fprintf(os.Stderr, "Failed to send error message via %s: %s\n", dl.name, err)
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "Failed to send error message via %s: %s - retrying in %s...\n", dl.name, err, backoff)
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// Start a delivery.
+func (d *Dispatcher) start(dl *delivery) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return fmt.Errorf("dispatcher is closed")
+	}
+
+	d.pending[dl] = struct{}{}
+	d.wg.Add(1)
+	go d.deliver(dl)
+
+	return nil
+}
+
+// Handle error.
+//
+// Starts delivering the error to all handlers and returns immediately.
+func (d *Dispatcher) Handle(err *Error) error {
+	for i, h := range d.handlers {
+		if startErr := d.start(&delivery{
+			handler: h,
+			name:    d.names[i],
+			err:     err,
+		}); startErr != nil {
+			return startErr
+		}
+	}
+
+	return nil
+}
+
+// Spool a delivery.
+//
+// The path of the delivery is set to the spooled error, so it is removed if a
+// running attempt still delivers the error.
+func (d *Dispatcher) spool(dl *delivery) error {
+	// Spooled deliveries which failed again are already on disk, and only
+	// need to be released for the next dispatcher to claim.
+	if dl.path != "" {
+		path := unclaimedPath(dl.path)
+		if err := os.Rename(dl.path, path); err != nil {
+			return err
+		}
+
+		dl.path = path
+		return nil
+	}
+
+	data, err := json.Marshal(&spooledError{
+		Handler: dl.name,
+		Error:   dl.err,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.seq++
+	name := fmt.Sprintf("%d-%d-%d.json", time.Now().UnixNano(), os.Getpid(), d.seq)
+	tmpPath := filepath.Join(d.config.SpoolDir, "."+name)
+	path := filepath.Join(d.config.SpoolDir, name)
+
+	if err = ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	dl.path = path
+	return nil
+}
+
+// Path of a claimed spooled error before it was claimed.
+func unclaimedPath(path string) string {
+	path = strings.TrimSuffix(path, claimedSuffix)
+	return path[:strings.LastIndexByte(path, '.')]
+}
+
+// Test if a spooled error is claimed by a running process.
+func claimedByRunningProcess(path string) bool {
+	path = strings.TrimSuffix(path, claimedSuffix)
+
+	pid, err := strconv.Atoi(path[strings.LastIndexByte(path, '.')+1:])
+	if err != nil {
+		return false
+	}
+
+	if pid == os.Getpid() {
+		return true
+	}
+
+	p, err := os.FindProcess(pid)
+	return err == nil && p.Signal(syscall.Signal(0)) == nil
+}
+
+// Claim a spooled error for delivery.
+//
+// The error is renamed, so no other dispatcher claims it. Errors claimed by
+// processes which are no longer running may be claimed again. Returns the
+// path of the claimed error.
+func claimSpooled(path string) (string, error) {
+	unclaimed := path
+	if strings.HasSuffix(path, claimedSuffix) {
+		unclaimed = unclaimedPath(path)
+	}
+
+	claimedPath := fmt.Sprintf("%s.%d%s", unclaimed, os.Getpid(), claimedSuffix)
+	if err := os.Rename(path, claimedPath); err != nil {
+		return "", err
+	}
+
+	return claimedPath, nil
+}
+
+// Load spooled errors.
+//
+// Starts delivering spooled errors to the handlers they were originally meant
+// for. Errors are claimed before being read, so they are delivered by a
+// single dispatcher, and errors which have disappeared in the meantime are
+// skipped. Errors for handlers that are no longer configured are left in
+// place.
+func (d *Dispatcher) loadSpool() error {
+	paths, err := filepath.Glob(filepath.Join(d.config.SpoolDir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	claimed, err := filepath.Glob(filepath.Join(d.config.SpoolDir, "*.json.*"+claimedSuffix))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range claimed {
+		if !claimedByRunningProcess(path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	handlers := make(map[string]int, len(d.handlers))
+	for i, name := range d.names {
+		handlers[name] = i
+	}
+
+	for _, path := range paths {
+		// Skip errors being spooled.
+		if strings.HasPrefix(filepath.Base(path), ".") {
+			continue
+		}
+
+		claimedPath, err := claimSpooled(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to claim spooled error message %s: %s\n", path, err)
+			continue
+		}
+
+		release := func() {
+			os.Rename(claimedPath, unclaimedPath(claimedPath))
+		}
+
+		data, err := ioutil.ReadFile(claimedPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read spooled error message %s: %s\n", path, err)
+			release()
+			continue
+		}
+
+		var spooled spooledError
+		if err = json.Unmarshal(data, &spooled); err != nil || spooled.Error == nil {
+			fmt.Fprintf(os.Stderr, "Ignoring invalid spooled error message %s\n", path)
+			release()
+			continue
+		}
+
+		i, ok := handlers[spooled.Handler]
+		if !ok {
+			release()
+			continue
+		}
+
+		if err = d.start(&delivery{
+			handler: d.handlers[i],
+			name:    d.names[i],
+			err:     spooled.Error,
+			path:    claimedPath,
+		}); err != nil {
+			release()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close the dispatcher.
+//
+// Waits for pending deliveries to finish until the deadline is reached. Any
+// errors not delivered by then are spooled if a spool directory is
+// configured.
+func (d *Dispatcher) Close() error {
+	d.mutex.Lock()
+	d.closed = true
+	d.mutex.Unlock()
+
+	// Wait for the deliveries to finish.
+	finished := make(chan struct{})
+
+	go func() {
+		d.wg.Wait()
+		close(finished)
+	}()
+
+	timer := time.NewTimer(d.config.Deadline)
+	defer timer.Stop()
+
+	select {
+	case <-finished:
+	case <-timer.C:
+	}
+
+	// Spool any undelivered errors.
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if len(d.pending) == 0 {
+		return nil
+	}
+
+	if d.config.SpoolDir == "" {
+		return fmt.Errorf("%d error message(s) could not be delivered", len(d.pending))
+	}
+
+	var failed []string
+	for dl := range d.pending {
+		if err := d.spool(dl); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	d.pending = make(map[*delivery]struct{})
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to spool undelivered error message(s): %s", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// New dispatcher.
+//
+// If a spool directory is configured, it is created if necessary and errors
+// spooled by previous dispatchers are redelivered.
+func NewDispatcher(handlers []Handler, config DispatcherConfig) (*Dispatcher, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.Retries < 0 {
+		return nil, fmt.Errorf("retries cannot be negative")
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = time.Second
+	}
+	if config.Deadline <= 0 {
+		config.Deadline = 30 * time.Second
+	}
+
+	d := &Dispatcher{
+		handlers: handlers,
+		names:    handlerNames(handlers),
+		config:   config,
+		pending:  make(map[*delivery]struct{}),
+	}
+
+	if config.SpoolDir != "" {
+		if err := os.MkdirAll(config.SpoolDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create spool directory: %s", err)
+		}
+
+		if err := d.loadSpool(); err != nil {
+			return nil, fmt.Errorf("failed to load spooled error messages: %s", err)
+		}
+	}
+
+	return d, nil
+}
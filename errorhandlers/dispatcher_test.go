@@ -0,0 +1,307 @@
+package errorhandlers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test handler failing a number of times before succeeding.
+type testHandler struct {
+	failures int
+	handled  []*Error
+	mutex    sync.Mutex
+}
+
+func (h *testHandler) Handle(err *Error) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.failures > 0 {
+		h.failures--
+		return fmt.Errorf("failure")
+	}
+
+	h.handled = append(h.handled, err)
+	return nil
+}
+
+func (h *testHandler) String() string {
+	return "test"
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	h := &testHandler{failures: 2}
+	d, err := NewDispatcher([]Handler{h}, DispatcherConfig{
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %s", err)
+	}
+
+	d.Handle(&Error{Desc: "test"})
+
+	if err = d.Close(); err != nil {
+		t.Errorf("Expected closing to succeed, but got: %s", err)
+	}
+	if len(h.handled) != 1 {
+		t.Errorf("Expected error to be handled once, but was handled %d time(s)", len(h.handled))
+	}
+}
+
+func TestDispatcherSpool(t *testing.T) {
+	spoolDir, err := ioutil.TempDir("", "coyote-spool")
+	if err != nil {
+		t.Fatalf("Failed to create spool directory: %s", err)
+	}
+	defer os.RemoveAll(spoolDir)
+
+	// Fail delivery, which should spool the error.
+	h := &testHandler{failures: 1}
+	d, err := NewDispatcher([]Handler{h}, DispatcherConfig{
+		Retries:  0,
+		SpoolDir: spoolDir,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %s", err)
+	}
+
+	d.Handle(&Error{Desc: "spooled"})
+
+	if err = d.Close(); err != nil {
+		t.Errorf("Expected closing to succeed, but got: %s", err)
+	}
+	if files, _ := ioutil.ReadDir(spoolDir); len(files) != 1 {
+		t.Fatalf("Expected 1 spooled error, but found %d", len(files))
+	}
+
+	// Creating a new dispatcher should deliver the spooled error.
+	d, err = NewDispatcher([]Handler{h}, DispatcherConfig{
+		SpoolDir: spoolDir,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %s", err)
+	}
+
+	if err = d.Close(); err != nil {
+		t.Errorf("Expected closing to succeed, but got: %s", err)
+	}
+	if len(h.handled) != 1 || h.handled[0].Desc != "spooled" {
+		t.Errorf("Expected spooled error to be delivered, but got %v", h.handled)
+	}
+	if files, _ := ioutil.ReadDir(spoolDir); len(files) != 0 {
+		t.Errorf("Expected spool to be empty, but found %d file(s)", len(files))
+	}
+}
+
+// Test handler blocking until released.
+type blockingHandler struct {
+	release chan struct{}
+	handled chan *Error
+}
+
+func (h *blockingHandler) Handle(err *Error) error {
+	<-h.release
+	h.handled <- err
+	return nil
+}
+
+func TestDispatcherLateDelivery(t *testing.T) {
+	spoolDir, err := ioutil.TempDir("", "coyote-spool")
+	if err != nil {
+		t.Fatalf("Failed to create spool directory: %s", err)
+	}
+	defer os.RemoveAll(spoolDir)
+
+	h := &blockingHandler{
+		release: make(chan struct{}),
+		handled: make(chan *Error, 1),
+	}
+	d, err := NewDispatcher([]Handler{h}, DispatcherConfig{
+		Deadline: 10 * time.Millisecond,
+		SpoolDir: spoolDir,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %s", err)
+	}
+
+	d.Handle(&Error{Desc: "late"})
+
+	// Closing before delivery spools the error.
+	if err = d.Close(); err != nil {
+		t.Errorf("Expected closing to succeed, but got: %s", err)
+	}
+	if files, _ := ioutil.ReadDir(spoolDir); len(files) != 1 {
+		t.Fatalf("Expected 1 spooled error, but found %d", len(files))
+	}
+
+	// Delivering the error afterwards removes it from the spool.
+	close(h.release)
+	<-h.handled
+
+	for i := 0; i < 100; i++ {
+		if files, _ := ioutil.ReadDir(spoolDir); len(files) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("Expected spool to be empty after late delivery")
+}
+
+// Test handler taking a while to deliver errors.
+type slowHandler struct {
+	delay       time.Duration
+	cancellable bool
+	attempts    int
+	handled     []*Error
+	mutex       sync.Mutex
+}
+
+func (h *slowHandler) Handle(err *Error) error {
+	time.Sleep(h.delay)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.attempts++
+	h.handled = append(h.handled, err)
+	return nil
+}
+
+// Cancellable slow test handler.
+//
+// Only the first attempt is slow.
+type cancellableSlowHandler struct {
+	slowHandler
+}
+
+func (h *cancellableSlowHandler) HandleContext(ctx context.Context, err *Error) error {
+	h.mutex.Lock()
+	h.attempts++
+	first := h.attempts == 1
+	h.mutex.Unlock()
+
+	if first {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(h.delay):
+		}
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.handled = append(h.handled, err)
+	return nil
+}
+
+func TestDispatcherSlowHandler(t *testing.T) {
+	slow := &slowHandler{delay: 100 * time.Millisecond}
+	cancellable := &cancellableSlowHandler{slowHandler{delay: time.Minute}}
+
+	d, err := NewDispatcher([]Handler{slow, cancellable}, DispatcherConfig{
+		Timeout: 10 * time.Millisecond,
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %s", err)
+	}
+
+	d.Handle(&Error{Desc: "test"})
+
+	if err = d.Close(); err != nil {
+		t.Errorf("Expected closing to succeed, but got: %s", err)
+	}
+
+	// Attempts which cannot be cancelled are waited for rather than retried.
+	if slow.attempts != 1 || len(slow.handled) != 1 {
+		t.Errorf("Expected error to be handled once in 1 attempt, but was handled %d time(s) in %d attempt(s)", len(slow.handled), slow.attempts)
+	}
+
+	// Attempts which can be cancelled are aborted and retried.
+	if cancellable.attempts != 2 || len(cancellable.handled) != 1 {
+		t.Errorf("Expected error to be handled once in 2 attempts, but was handled %d time(s) in %d attempt(s)", len(cancellable.handled), cancellable.attempts)
+	}
+}
+
+func TestDispatcherLoadSpool(t *testing.T) {
+	spoolDir, err := ioutil.TempDir("", "coyote-spool")
+	if err != nil {
+		t.Fatalf("Failed to create spool directory: %s", err)
+	}
+	defer os.RemoveAll(spoolDir)
+
+	write := func(name, desc string) {
+		data := fmt.Sprintf(`{"handler":"test","error":{"desc":%q}}`, desc)
+		if err := ioutil.WriteFile(filepath.Join(spoolDir, name), []byte(data), 0600); err != nil {
+			t.Fatalf("Failed to write spooled error: %s", err)
+		}
+	}
+
+	// Errors claimed by running processes and errors being spooled are
+	// skipped, while errors claimed by processes no longer running are
+	// claimed again. Unreadable errors are left in place.
+	write("1-1-1.json", "spooled")
+	write(fmt.Sprintf("2-1-1.json.%d.claimed", os.Getpid()), "claimed")
+	write("3-1-1.json.2147483646.claimed", "abandoned")
+	write(".4-1-1.json", "spooling")
+	if err = os.Mkdir(filepath.Join(spoolDir, "5-1-1.json"), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+
+	h := &testHandler{}
+	d, err := NewDispatcher([]Handler{h}, DispatcherConfig{
+		SpoolDir: spoolDir,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %s", err)
+	}
+
+	if err = d.Close(); err != nil {
+		t.Errorf("Expected closing to succeed, but got: %s", err)
+	}
+
+	var delivered []string
+	for _, err := range h.handled {
+		delivered = append(delivered, err.Desc)
+	}
+	if !reflect.DeepEqual(delivered, []string{"spooled", "abandoned"}) && !reflect.DeepEqual(delivered, []string{"abandoned", "spooled"}) {
+		t.Errorf("Expected spooled and abandoned errors to be delivered, but got %q", delivered)
+	}
+
+	var left []string
+	files, _ := ioutil.ReadDir(spoolDir)
+	for _, fi := range files {
+		left = append(left, fi.Name())
+	}
+	expected := []string{".4-1-1.json", fmt.Sprintf("2-1-1.json.%d.claimed", os.Getpid()), "5-1-1.json"}
+	if !reflect.DeepEqual(left, expected) {
+		t.Errorf("Expected %q to be left in the spool, but got %q", expected, left)
+	}
+}
+
+// Test handler without a description.
+type anonymousHandler struct{}
+
+func (h anonymousHandler) Handle(err *Error) error {
+	return nil
+}
+
+func TestHandlerNames(t *testing.T) {
+	names := handlerNames([]Handler{&testHandler{}, anonymousHandler{}, &testHandler{}, anonymousHandler{}})
+	expected := []string{"test", "errorhandlers.anonymousHandler", "test #2", "errorhandlers.anonymousHandler #3"}
+
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected handler names %q, but got %q", expected, names)
+	}
+}
@@ -0,0 +1,320 @@
+package errorhandlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Email text body template.
+var emailTextTemplate = texttemplate.Must(texttemplate.New("text").Parse(`The command {{.Cmd}} failed on {{.Hostname}} at {{.Timestamp}}.
+
+Error: {{.Desc}}
+Exit status: {{.ExitStatus}}
+{{if .Signal}}Signal: {{.Signal}}{{if .CoreDumped}} (core dumped){{end}}
+{{end}}{{if .Duration}}Duration: {{.Duration}}
+CPU time: {{.UserTime}} user, {{.SystemTime}} system
+{{end}}{{if .MaxRss}}Max RSS: {{.MaxRss}} bytes
+{{end}}{{if .Output}}
+Output:
+
+{{range .Output}}    {{.}}
+{{end}}{{end}}
+Environment:
+
+{{range .Environ}}    {{.Key}}={{.Value}}
+{{end}}`))
+
+// Email HTML body template.
+var emailHtmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<p>The command <code>{{.Cmd}}</code> failed on <strong>{{.Hostname}}</strong> at {{.Timestamp}}.</p>
+<table>
+<tr><th align="left">Error</th><td>{{.Desc}}</td></tr>
+<tr><th align="left">Exit status</th><td>{{.ExitStatus}}</td></tr>
+{{if .Signal}}<tr><th align="left">Signal</th><td>{{.Signal}}{{if .CoreDumped}} (core dumped){{end}}</td></tr>
+{{end}}{{if .Duration}}<tr><th align="left">Duration</th><td>{{.Duration}}</td></tr>
+<tr><th align="left">CPU time</th><td>{{.UserTime}} user, {{.SystemTime}} system</td></tr>
+{{end}}{{if .MaxRss}}<tr><th align="left">Max RSS</th><td>{{.MaxRss}} bytes</td></tr>
+{{end}}</table>
+{{if .Output}}<h3>Output</h3>
+<pre>{{range .Output}}{{.}}
+{{end}}</pre>
+{{end}}<h3>Environment</h3>
+<table>
+{{range .Environ}}<tr><td><code>{{.Key}}</code></td><td><code>{{.Value}}</code></td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// Email template context.
+type emailTemplateContext struct {
+	Cmd        string
+	Desc       string
+	Hostname   string
+	Timestamp  string
+	ExitStatus int
+	Signal     string
+	CoreDumped bool
+	Duration   time.Duration
+	UserTime   time.Duration
+	SystemTime time.Duration
+	MaxRss     int64
+	Output     []string
+	Environ    []filteredEnvironVar
+}
+
+// Email error handler.
+type emailErrorHandler struct {
+	address     string
+	host        string
+	username    string
+	password    string
+	from        string
+	fromAddr    string
+	to          []string
+	toAddrs     []string
+	implicitTls bool
+	timeout     time.Duration
+}
+
+// Build the email message for an error.
+func (h *emailErrorHandler) message(errMsg *Error) ([]byte, error) {
+	hostname := errMsg.Hostname
+	if hostname == "" {
+		hostname = "unknown host"
+	}
+
+	ctx := &emailTemplateContext{
+		Cmd:        errMsg.QuotedCmd(),
+		Desc:       errMsg.Desc,
+		Hostname:   hostname,
+		Timestamp:  errMsg.Timestamp.Format(time.RFC1123Z),
+		ExitStatus: errMsg.ExitStatus,
+		Signal:     errMsg.Signal,
+		CoreDumped: errMsg.CoreDumped,
+		Duration:   errMsg.Duration,
+		UserTime:   errMsg.UserTime,
+		SystemTime: errMsg.SystemTime,
+		MaxRss:     errMsg.MaxRss,
+		Output:     errMsg.Output,
+		Environ:    filterEnviron(errMsg.Environ),
+	}
+
+	// Render the bodies.
+	var textBody, htmlBody bytes.Buffer
+
+	if err := emailTextTemplate.Execute(&textBody, ctx); err != nil {
+		return nil, err
+	}
+	if err := emailHtmlTemplate.Execute(&htmlBody, ctx); err != nil {
+		return nil, err
+	}
+
+	// Write the headers.
+	var msg bytes.Buffer
+	body := multipart.NewWriter(&msg)
+
+	subject := truncateString(fmt.Sprintf("[coyote] %s failed on %s", ctx.Cmd, hostname), 200)
+
+	fmt.Fprintf(&msg, "From: %s\r\n", h.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(h.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", errMsg.Timestamp.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", body.Boundary())
+
+	// Write the parts.
+	for _, part := range []struct {
+		contentType string
+		data        []byte
+	}{
+		{"text/plain; charset=utf-8", textBody.Bytes()},
+		{"text/html; charset=utf-8", htmlBody.Bytes()},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(w)
+		if _, err = qw.Write(part.data); err != nil {
+			return nil, err
+		}
+		if err = qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	return msg.Bytes(), nil
+}
+
+func (h *emailErrorHandler) Handle(errMsg *Error) error {
+	return h.HandleContext(context.Background(), errMsg)
+}
+
+func (h *emailErrorHandler) HandleContext(ctx context.Context, errMsg *Error) error {
+	msg, err := h.message(errMsg)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the server. The deadline bounds the entire conversation, so
+	// an unresponsive server cannot block us indefinitely.
+	deadline := time.Now().Add(h.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	dialer := &net.Dialer{
+		Deadline: deadline,
+	}
+	tlsConfig := &tls.Config{
+		ServerName: h.host,
+	}
+
+	var conn net.Conn
+	if h.implicitTls {
+		conn, err = tls.DialWithDialer(dialer, "tcp", h.address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", h.address)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	// Abort the conversation if the context is done before the deadline.
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, h.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// Upgrade the connection if the server supports it.
+	if !h.implicitTls {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err = c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Authenticate.
+	if h.username != "" {
+		if err = c.Auth(smtp.PlainAuth("", h.username, h.password, h.host)); err != nil {
+			return err
+		}
+	}
+
+	// Send the message.
+	if err = c.Mail(h.fromAddr); err != nil {
+		return err
+	}
+
+	for _, to := range h.toAddrs {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func (h *emailErrorHandler) String() string {
+	return fmt.Sprintf("email to %s via %s", strings.Join(h.to, ", "), h.address)
+}
+
+// New email error handler.
+//
+// Sends a plain text and HTML email describing the error to the recipients
+// through the SMTP server at the address. If implicit TLS is not used, the
+// connection is upgraded with STARTTLS if the server supports it. Delivery
+// fails if it takes longer than the timeout.
+func NewEmailErrorHandler(address, username, password, from string, to []string, implicitTls bool, timeout time.Duration) (Handler, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP server address: %s", err)
+	}
+	if from == "" {
+		return nil, fmt.Errorf("sender address cannot be empty")
+	}
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %s", err)
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("no recipients provided")
+	}
+	toAddrs := make([]string, 0, len(to))
+	for _, r := range to {
+		addr, err := mail.ParseAddress(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient address %q: %s", r, err)
+		}
+		toAddrs = append(toAddrs, addr.Address)
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be positive")
+	}
+
+	return &emailErrorHandler{
+		address:     address,
+		host:        host,
+		username:    username,
+		password:    password,
+		from:        from,
+		fromAddr:    fromAddr.Address,
+		to:          to,
+		toAddrs:     toAddrs,
+		implicitTls: implicitTls,
+		timeout:     timeout,
+	}, nil
+}
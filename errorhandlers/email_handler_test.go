@@ -0,0 +1,114 @@
+package errorhandlers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailErrorHandlerMessage(t *testing.T) {
+	h, err := NewEmailErrorHandler("mail.example.com:25", "", "", "Coyote <coyote@example.com>", []string{"ops@example.com", "dev@example.com"}, false, time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %s", err)
+	}
+
+	data, err := h.(*emailErrorHandler).message(&Error{
+		Cmd:        []string{"myapp", "--name", "Åse <script>"},
+		Desc:       "process exited with status 3",
+		ExitStatus: 3,
+		Duration:   1500 * time.Millisecond,
+		Output:     []string{"starting", "disk <full>"},
+		Hostname:   "web1",
+		Environ:    map[string]string{"PATH": "/bin", "DB_PASSWORD": "hunter2"},
+		Timestamp:  time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Expected building the message to succeed, but got: %s", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected a valid message, but got: %s", err)
+	}
+
+	// The headers.
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("Expected a valid subject, but got: %s", err)
+	}
+
+	for header, expected := range map[string]string{
+		"From":    "Coyote <coyote@example.com>",
+		"To":      "ops@example.com, dev@example.com",
+		"Subject": `[coyote] myapp --name "Åse <script>" failed on web1`,
+		"Date":    "Sat, 02 Jan 2016 03:04:05 +0000",
+	} {
+		actual := msg.Header.Get(header)
+		if header == "Subject" {
+			actual = subject
+		}
+
+		if actual != expected {
+			t.Errorf("Expected %s header %q, but got %q", header, expected, actual)
+		}
+	}
+
+	// The parts.
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected a multipart/alternative message, but got %q", msg.Header.Get("Content-Type"))
+	}
+
+	parts := make(map[string]string)
+	r := multipart.NewReader(msg.Body, params["boundary"])
+
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+
+		body, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatalf("Failed to read part: %s", err)
+		}
+
+		// Line breaks are encoded as CRLF.
+		parts[p.Header.Get("Content-Type")] = strings.Replace(string(body), "\r\n", "\n", -1)
+	}
+
+	text := parts["text/plain; charset=utf-8"]
+	for _, expected := range []string{
+		`The command myapp --name "Åse <script>" failed on web1 at Sat, 02 Jan 2016 03:04:05 +0000.`,
+		"Error: process exited with status 3\n",
+		"Exit status: 3\n",
+		"Duration: 1.5s\n",
+		"    starting\n    disk <full>\n",
+		"    DB_PASSWORD=[REDACTED]\n    PATH=/bin\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected text body to contain %q, but got:\n%s", expected, text)
+		}
+	}
+
+	html := parts["text/html; charset=utf-8"]
+	for _, expected := range []string{
+		"<code>myapp --name &#34;Åse &lt;script&gt;&#34;</code>",
+		"<td>process exited with status 3</td>",
+		"<pre>starting\ndisk &lt;full&gt;\n</pre>",
+		"<code>DB_PASSWORD</code></td><td><code>[REDACTED]</code>",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected HTML body to contain %q, but got:\n%s", expected, html)
+		}
+	}
+
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected sensitive environment variables to be redacted")
+	}
+}
@@ -8,19 +8,53 @@ import (
 // Error.
 type Error struct {
 	// Command.
-	Cmd []string
+	Cmd []string `json:"cmd"`
 
 	// Description.
-	Desc string
+	Desc string `json:"desc"`
+
+	// Exit status.
+	//
+	// -1 if the process was terminated by a signal or never started.
+	ExitStatus int `json:"exit_status"`
+
+	// Signal which terminated the process, such as SIGKILL.
+	//
+	// Empty if the process was not terminated by a signal.
+	Signal string `json:"signal,omitempty"`
+
+	// Whether the process dumped core.
+	CoreDumped bool `json:"core_dumped"`
+
+	// Wall-clock duration of the process.
+	//
+	// Zero if the process never started or has not exited.
+	Duration time.Duration `json:"duration"`
+
+	// Maximum resident set size of the process in bytes.
+	//
+	// Zero if not available.
+	MaxRss int64 `json:"max_rss"`
+
+	// User CPU time of the process.
+	UserTime time.Duration `json:"user_time"`
+
+	// System CPU time of the process.
+	SystemTime time.Duration `json:"system_time"`
+
+	// Output.
+	//
+	// The last lines of output from the process, oldest first.
+	Output []string `json:"output"`
 
 	// Hostname.
-	Hostname string
+	Hostname string `json:"hostname"`
 
 	// Environment.
-	Environ map[string]string
+	Environ map[string]string `json:"environ"`
 
 	// Timestamp.
-	Timestamp time.Time
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // Test if a command part should be quoted.
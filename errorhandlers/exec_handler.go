@@ -0,0 +1,131 @@
+package errorhandlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the environment variables passing the error fields to commands
+// run by exec error handlers.
+var ExecEnvironVars = []string{
+	"COYOTE_CMD",
+	"COYOTE_DESC",
+	"COYOTE_EXIT_STATUS",
+	"COYOTE_SIGNAL",
+	"COYOTE_CORE_DUMPED",
+	"COYOTE_DURATION",
+	"COYOTE_MAX_RSS",
+	"COYOTE_USER_TIME",
+	"COYOTE_SYSTEM_TIME",
+	"COYOTE_OUTPUT",
+	"COYOTE_HOSTNAME",
+	"COYOTE_TIMESTAMP",
+}
+
+// JSON-encoded error passed to commands on stdin.
+//
+// Durations are in seconds, like in the environment variables, and the
+// environment is filtered like in emails.
+type execErrorJson struct {
+	*Error
+	Duration   float64           `json:"duration"`
+	UserTime   float64           `json:"user_time"`
+	SystemTime float64           `json:"system_time"`
+	Environ    map[string]string `json:"environ"`
+}
+
+// Exec error handler.
+type execErrorHandler struct {
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+func (h *execErrorHandler) Handle(errMsg *Error) error {
+	return h.HandleContext(context.Background(), errMsg)
+}
+
+func (h *execErrorHandler) HandleContext(parent context.Context, errMsg *Error) error {
+	environ := make(map[string]string, len(errMsg.Environ))
+	for _, v := range filterEnviron(errMsg.Environ) {
+		environ[v.Key] = v.Value
+	}
+
+	data, err := json.Marshal(&execErrorJson{
+		Error:      errMsg,
+		Duration:   errMsg.Duration.Seconds(),
+		UserTime:   errMsg.UserTime.Seconds(),
+		SystemTime: errMsg.SystemTime.Seconds(),
+		Environ:    environ,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(parent, h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.path, h.args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"COYOTE_CMD="+errMsg.QuotedCmd(),
+		"COYOTE_DESC="+errMsg.Desc,
+		"COYOTE_EXIT_STATUS="+strconv.Itoa(errMsg.ExitStatus),
+		"COYOTE_SIGNAL="+errMsg.Signal,
+		"COYOTE_CORE_DUMPED="+strconv.FormatBool(errMsg.CoreDumped),
+		"COYOTE_DURATION="+strconv.FormatFloat(errMsg.Duration.Seconds(), 'f', -1, 64),
+		"COYOTE_MAX_RSS="+strconv.FormatInt(errMsg.MaxRss, 10),
+		"COYOTE_USER_TIME="+strconv.FormatFloat(errMsg.UserTime.Seconds(), 'f', -1, 64),
+		"COYOTE_SYSTEM_TIME="+strconv.FormatFloat(errMsg.SystemTime.Seconds(), 'f', -1, 64),
+		"COYOTE_OUTPUT="+strings.Join(errMsg.Output, "\n"),
+		"COYOTE_HOSTNAME="+errMsg.Hostname,
+		"COYOTE_TIMESTAMP="+errMsg.Timestamp.Format(time.RFC3339),
+	)
+
+	if err = cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+			return fmt.Errorf("error hook %s timed out after %s", h.path, h.timeout)
+		}
+
+		return fmt.Errorf("error hook %s failed: %s", h.path, err)
+	}
+
+	return nil
+}
+
+func (h *execErrorHandler) String() string {
+	return fmt.Sprintf("error hook %s", h.path)
+}
+
+// New exec error handler.
+//
+// Runs the command at the path with the error fields passed as COYOTE_CMD,
+// COYOTE_DESC, COYOTE_EXIT_STATUS, COYOTE_SIGNAL, COYOTE_CORE_DUMPED,
+// COYOTE_DURATION, COYOTE_MAX_RSS, COYOTE_USER_TIME, COYOTE_SYSTEM_TIME,
+// COYOTE_OUTPUT, COYOTE_HOSTNAME and COYOTE_TIMESTAMP environment variables,
+// with durations in seconds, and the JSON-encoded error on stdin, also with
+// durations in seconds and with likely sensitive environment variables
+// redacted. The command is killed if it runs for longer than the timeout.
+func NewExecErrorHandler(path string, args []string, timeout time.Duration) (Handler, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path cannot be empty")
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be positive")
+	}
+
+	return &execErrorHandler{
+		path:    path,
+		args:    args,
+		timeout: timeout,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package errorhandlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecErrorHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coyote-hook")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := NewExecErrorHandler("/bin/sh", []string{"-c", `cat > "$0/stdin" && env -0 > "$0/env"`, dir}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %s", err)
+	}
+
+	if err = h.Handle(&Error{
+		Cmd:        []string{"myapp", "-v"},
+		Desc:       "process exited with status 3",
+		ExitStatus: 3,
+		Duration:   1500 * time.Millisecond,
+		UserTime:   250 * time.Millisecond,
+		Output:     []string{"a", "b"},
+		Hostname:   "web1",
+		Environ:    map[string]string{"PATH": "/bin", "API_TOKEN": "secret"},
+		Timestamp:  time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+	}); err != nil {
+		t.Fatalf("Expected handling to succeed, but got: %s", err)
+	}
+
+	// The error on stdin.
+	data, err := ioutil.ReadFile(filepath.Join(dir, "stdin"))
+	if err != nil {
+		t.Fatalf("Failed to read stdin of hook: %s", err)
+	}
+
+	var payload map[string]interface{}
+	if err = json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("Expected JSON on stdin, but got %q", data)
+	}
+
+	for key, expected := range map[string]interface{}{
+		"cmd":         []interface{}{"myapp", "-v"},
+		"desc":        "process exited with status 3",
+		"exit_status": 3.0,
+		"duration":    1.5,
+		"user_time":   0.25,
+		"system_time": 0.0,
+		"output":      []interface{}{"a", "b"},
+		"hostname":    "web1",
+		"environ":     map[string]interface{}{"PATH": "/bin", "API_TOKEN": "[REDACTED]"},
+		"timestamp":   "2016-01-02T03:04:05Z",
+	} {
+		if !reflect.DeepEqual(payload[key], expected) {
+			t.Errorf("Expected %s on stdin to be %v, but got %v", key, expected, payload[key])
+		}
+	}
+
+	// The environment variables.
+	env, err := ioutil.ReadFile(filepath.Join(dir, "env"))
+	if err != nil {
+		t.Fatalf("Failed to read environment of hook: %s", err)
+	}
+
+	var vars []string
+	for _, v := range strings.Split(string(env), "\x00") {
+		if strings.HasPrefix(v, "COYOTE_") {
+			vars = append(vars, v)
+		}
+	}
+	sort.Strings(vars)
+
+	expected := []string{
+		"COYOTE_CMD=myapp -v",
+		"COYOTE_CORE_DUMPED=false",
+		"COYOTE_DESC=process exited with status 3",
+		"COYOTE_DURATION=1.5",
+		"COYOTE_EXIT_STATUS=3",
+		"COYOTE_HOSTNAME=web1",
+		"COYOTE_MAX_RSS=0",
+		"COYOTE_OUTPUT=a\nb",
+		"COYOTE_SIGNAL=",
+		"COYOTE_SYSTEM_TIME=0",
+		"COYOTE_TIMESTAMP=2016-01-02T03:04:05Z",
+		"COYOTE_USER_TIME=0.25",
+	}
+
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Expected environment variables %q, but got %q", expected, vars)
+	}
+}
@@ -1,7 +1,19 @@
 package errorhandlers
 
+import (
+	"context"
+)
+
 // Error handler.
 type Handler interface {
 	// Handle error.
 	Handle(err *Error) error
 }
+
+// Cancellable error handler.
+type ContextHandler interface {
+	Handler
+
+	// Handle error, aborting once the context is done.
+	HandleContext(ctx context.Context, err *Error) error
+}
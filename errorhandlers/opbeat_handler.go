@@ -2,12 +2,22 @@ package errorhandlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 )
 
+// Opbeat HTTP client.
+//
+// Bounds requests, so an unresponsive Opbeat endpoint cannot block delivery
+// indefinitely.
+var opbeatHttpClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
 // Opbeat error handler.
 type opbeatErrorHandler struct {
 	appId          string
@@ -16,8 +26,12 @@ type opbeatErrorHandler struct {
 }
 
 func (h *opbeatErrorHandler) Handle(errMsg *Error) error {
+	return h.HandleContext(context.Background(), errMsg)
+}
+
+func (h *opbeatErrorHandler) HandleContext(ctx context.Context, errMsg *Error) error {
 	// Construct the payload.
-	extra := make(map[string]string, len(errMsg.Environ))
+	extra := make(map[string]interface{}, len(errMsg.Environ)+7)
 
 	for k, v := range errMsg.Environ {
 		if isAscii(k) && isAscii(v) {
@@ -25,6 +39,16 @@ func (h *opbeatErrorHandler) Handle(errMsg *Error) error {
 		}
 	}
 
+	extra["exit_status"] = errMsg.ExitStatus
+	extra["core_dumped"] = errMsg.CoreDumped
+	extra["duration"] = errMsg.Duration.Seconds()
+	extra["max_rss"] = errMsg.MaxRss
+	extra["user_time"] = errMsg.UserTime.Seconds()
+	extra["system_time"] = errMsg.SystemTime.Seconds()
+	if errMsg.Signal != "" {
+		extra["signal"] = errMsg.Signal
+	}
+
 	payload := map[string]interface{}{
 		"message":   errMsg.Desc,
 		"culprit":   errMsg.QuotedCmd(),
@@ -48,14 +72,16 @@ func (h *opbeatErrorHandler) Handle(errMsg *Error) error {
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(ctx)
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", h.secretToken))
 	req.Header.Set("Content-Type", "application/json")
 	req.ContentLength = int64(len(data))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := opbeatHttpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	// Check the response.
 	if resp.StatusCode < 200 || resp.StatusCode > 300 {
@@ -79,6 +105,10 @@ func (h *opbeatErrorHandler) Handle(errMsg *Error) error {
 	return nil
 }
 
+func (h *opbeatErrorHandler) String() string {
+	return fmt.Sprintf("Opbeat app %s", h.appId)
+}
+
 // New Opbeat error handler.
 func NewOpbeatErrorHandler(appId, organizationId, secretToken string) (Handler, error) {
 	if appId == "" {
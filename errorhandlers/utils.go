@@ -1,5 +1,40 @@
 package errorhandlers
 
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Environment variable name fragments indicating sensitive values.
+var sensitiveEnvironFragments = []string{
+	"PASSWORD",
+	"PASSWD",
+	"SECRET",
+	"TOKEN",
+	"CREDENTIAL",
+	"PRIVATE",
+	"API_KEY",
+	"APIKEY",
+	"ACCESS_KEY",
+}
+
+// Truncate a string to a maximum length in bytes.
+//
+// Truncated strings end in an ellipsis, and are only cut between runes.
+func truncateString(str string, max int) string {
+	if len(str) <= max {
+		return str
+	}
+
+	end := max - 3
+	for end > 0 && !utf8.RuneStart(str[end]) {
+		end--
+	}
+
+	return str[:end] + "..."
+}
+
 func isAscii(str string) bool {
 	for _, c := range str {
 		if c <= 31 || c >= 127 {
@@ -9,3 +44,48 @@ func isAscii(str string) bool {
 
 	return true
 }
+
+// Test if an environment variable is likely to hold a sensitive value.
+func isSensitiveEnvironKey(key string) bool {
+	key = strings.ToUpper(key)
+
+	for _, f := range sensitiveEnvironFragments {
+		if strings.Contains(key, f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filtered environment variable.
+type filteredEnvironVar struct {
+	Key   string
+	Value string
+}
+
+// Filter environment.
+//
+// Returns the printable environment variables sorted by key with the values
+// of likely sensitive variables redacted.
+func filterEnviron(environ map[string]string) []filteredEnvironVar {
+	filtered := make([]filteredEnvironVar, 0, len(environ))
+
+	for k, v := range environ {
+		if !isAscii(k) || !isAscii(v) {
+			continue
+		}
+
+		if isSensitiveEnvironKey(k) {
+			v = "[REDACTED]"
+		}
+
+		filtered = append(filtered, filteredEnvironVar{k, v})
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].Key < filtered[j].Key
+	})
+
+	return filtered
+}
@@ -0,0 +1,29 @@
+package errorhandlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateString(t *testing.T) {
+	for _, tc := range []struct {
+		str      string
+		max      int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly 10", 10, "exactly 10"},
+		{"longer than 10", 10, "longer ..."},
+		{"abæøå", 6, "ab..."},
+		{"abæøå", 7, "abæ..."},
+		{"abcæøå", 8, "abcæ..."},
+	} {
+		if actual := truncateString(tc.str, tc.max); actual != tc.expected {
+			t.Errorf("Expected truncating %q to %d bytes to result in %q, but got %q", tc.str, tc.max, tc.expected, actual)
+		}
+	}
+
+	if actual := truncateString(strings.Repeat("æ", 150), 200); len(actual) > 200 || !strings.HasSuffix(actual, "æ...") {
+		t.Errorf("Expected truncation on a rune boundary, but got %q", actual)
+	}
+}
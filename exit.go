@@ -0,0 +1,101 @@
+package coyote
+
+import (
+	"syscall"
+	"time"
+)
+
+// Exit policy.
+//
+// Decides whether the way a process exited counts as a failure.
+type ExitPolicy struct {
+	// Exit codes considered successful in addition to 0.
+	SuccessExitCodes map[int]bool
+
+	// Signals for which termination is not considered a failure.
+	IgnoredSignals map[syscall.Signal]bool
+}
+
+// Test if the process exit was expected.
+//
+// Termination by a signal forwarded to the process is always expected, while
+// a process stopped by the supervisor never is.
+func (p *ExitPolicy) expected(exit *processExit) bool {
+	if exit.waitErr != nil || exit.stopReason != "" || exit.oomKilled {
+		return false
+	}
+
+	status := exit.status
+	forwardedSig := exit.lastSig
+
+	if status.Signaled() {
+		sig := status.Signal()
+		return (forwardedSig != nil && sig == forwardedSig) || p.IgnoredSignals[sig]
+	}
+
+	if status.Exited() {
+		return status.ExitStatus() == 0 || p.SuccessExitCodes[status.ExitStatus()]
+	}
+
+	return false
+}
+
+// Exit result.
+//
+// Describes how a run of a supervised process ended.
+type ExitResult struct {
+	// Exit status.
+	//
+	// -1 if the process was terminated by a signal, and 1 if it could not be
+	// started. A process stopped by the supervisor never exits with status 0.
+	ExitStatus int
+
+	// Signal which terminated the process, if any.
+	Signal syscall.Signal
+
+	// Whether the process dumped core.
+	CoreDumped bool
+
+	// Whether the exit is considered a failure by the exit policy.
+	Failed bool
+
+	// Error describing how the process exited or why it could not be
+	// started. Nil if the process exited with status 0 without being stopped
+	// by the supervisor.
+	Err error
+
+	// Whether the process could not be started.
+	StartFailed bool
+
+	// Reason the process was stopped by the supervisor, if any.
+	StopReason string
+
+	// Whether the process was killed by the OOM killer.
+	OomKilled bool
+
+	// Wall-clock duration of the process.
+	Duration time.Duration
+
+	// Resource usage of the process, if available.
+	Rusage *syscall.Rusage
+}
+
+// New exit result for a process exit.
+func newExitResult(exit *processExit, policy *ExitPolicy) *ExitResult {
+	r := &ExitResult{
+		ExitStatus: exit.exitStatus(),
+		Failed:     !policy.expected(exit),
+		Err:        exit.err(),
+		StopReason: exit.stopReason,
+		OomKilled:  exit.oomKilled,
+		Duration:   exit.duration,
+		Rusage:     exit.rusage,
+	}
+
+	if exit.waitErr == nil && exit.status.Signaled() {
+		r.Signal = exit.status.Signal()
+		r.CoreDumped = exit.status.CoreDump()
+	}
+
+	return r
+}
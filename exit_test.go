@@ -0,0 +1,57 @@
+package coyote
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+)
+
+// Wait status of a process which exited with a code.
+func exitedStatus(code int) syscall.WaitStatus {
+	return syscall.WaitStatus(code << 8)
+}
+
+// Wait status of a process terminated by a signal.
+func signaledStatus(sig syscall.Signal) syscall.WaitStatus {
+	return syscall.WaitStatus(sig)
+}
+
+func TestExitPolicyExpected(t *testing.T) {
+	policy := &ExitPolicy{
+		SuccessExitCodes: map[int]bool{3: true},
+		IgnoredSignals:   map[syscall.Signal]bool{syscall.SIGUSR1: true},
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		exit     processExit
+		expected bool
+	}{
+		{"exit status 0", processExit{status: exitedStatus(0)}, true},
+		{"success exit code", processExit{status: exitedStatus(3)}, true},
+		{"other exit code", processExit{status: exitedStatus(1)}, false},
+		{"forwarded signal", processExit{status: signaledStatus(syscall.SIGTERM), lastSig: syscall.SIGTERM}, true},
+		{"signal not forwarded", processExit{status: signaledStatus(syscall.SIGTERM)}, false},
+		{"other signal than forwarded", processExit{status: signaledStatus(syscall.SIGPIPE), lastSig: syscall.SIGTERM}, false},
+		{"ignored signal", processExit{status: signaledStatus(syscall.SIGUSR1)}, true},
+		{"stopped by the supervisor", processExit{status: exitedStatus(0), stopReason: "timed out"}, false},
+		{"killed by the OOM killer", processExit{status: signaledStatus(syscall.SIGKILL), oomKilled: true}, false},
+		{"failure to wait", processExit{waitErr: errors.New("failure")}, false},
+	} {
+		if actual := policy.expected(&tc.exit); actual != tc.expected {
+			t.Errorf("Expected exit by %s to be expected: %t, but got %t", tc.desc, tc.expected, actual)
+		}
+	}
+
+	// Without success exit codes or ignored signals.
+	policy = &ExitPolicy{}
+
+	for _, exit := range []processExit{
+		{status: exitedStatus(3)},
+		{status: signaledStatus(syscall.SIGUSR1)},
+	} {
+		if policy.expected(&exit) {
+			t.Errorf("Expected exit with status %d to be unexpected by default", exit.status)
+		}
+	}
+}
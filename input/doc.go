@@ -0,0 +1,2 @@
+// Package input provides sources of lines other than processes.
+package input
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package input
+
+import (
+	"os"
+	"syscall"
+)
+
+// File identity.
+//
+// Returns the device and inode numbers of a file.
+func fileId(fi os.FileInfo) (dev, ino uint64) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev), uint64(st.Ino)
+	}
+
+	return 0, 0
+}
@@ -0,0 +1,20 @@
+package input
+
+// Line sink.
+type Sink func(line []byte)
+
+// Input.
+//
+// Produces lines from a source.
+type Input interface {
+	// Run the input.
+	//
+	// Sinks lines until the input is closed or fails. The sink is never
+	// called concurrently.
+	Run(sink Sink) error
+
+	// Close the input.
+	//
+	// Stops the input and waits for Run to return.
+	Close() error
+}
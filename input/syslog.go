@@ -0,0 +1,279 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Number of messages buffered between receiving and sinking them.
+const syslogBufferSize = 10240
+
+// Maximum size of a received syslog message.
+const maxSyslogMessageSize = 64 * 1024
+
+// Syslog input.
+type syslogInput struct {
+	network   string
+	address   string
+	listener  net.Listener
+	conn      net.PacketConn
+	messages  chan []byte
+	conns     map[net.Conn]struct{}
+	receiveWg sync.WaitGroup
+	done      chan struct{}
+	mutex     sync.Mutex
+	running   bool
+	closed    bool
+}
+
+// Queue a received message for sinking.
+func (i *syslogInput) receive(data []byte) {
+	if m, err := parseSyslogMessage(data); err == nil {
+		i.messages <- m.line()
+	} else if data = bytes.TrimRight(data, "\r\n\x00"); len(data) > 0 {
+		i.messages <- append([]byte(nil), data...)
+	}
+}
+
+// Receive datagrams.
+func (i *syslogInput) receiveDatagrams() {
+	defer i.receiveWg.Done()
+
+	buf := make([]byte, maxSyslogMessageSize)
+
+	for {
+		n, _, err := i.conn.ReadFrom(buf)
+		if n > 0 {
+			i.receive(buf[:n])
+		}
+
+		if err != nil {
+			if !i.isClosed() {
+				fmt.Fprintf(os.Stderr, "Failed to receive syslog message on %s: %s\n", i, err)
+			}
+			return
+		}
+	}
+}
+
+// Accept stream connections.
+func (i *syslogInput) accept() {
+	defer i.receiveWg.Done()
+
+	for {
+		conn, err := i.listener.Accept()
+		if err != nil {
+			if i.isClosed() {
+				return
+			}
+
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+
+			fmt.Fprintf(os.Stderr, "Failed to accept syslog connection on %s: %s\n", i, err)
+			return
+		}
+
+		i.mutex.Lock()
+		if i.closed {
+			i.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		i.conns[conn] = struct{}{}
+		i.receiveWg.Add(1)
+		i.mutex.Unlock()
+
+		go i.receiveStream(conn)
+	}
+}
+
+// Split syslog messages received over a stream.
+//
+// Messages are framed either by octet counting or by a trailing newline, as
+// described in RFC 6587.
+func splitSyslogStream(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	// Octet counting.
+	if data[0] >= '1' && data[0] <= '9' {
+		if sp := bytes.IndexByte(data, ' '); sp != -1 {
+			if length, err := strconv.Atoi(string(data[:sp])); err == nil {
+				if length > maxSyslogMessageSize {
+					return 0, nil, fmt.Errorf("message of %d bytes exceeds maximum size", length)
+				}
+
+				if len(data) < sp+1+length {
+					if atEOF {
+						return len(data), data[sp+1:], nil
+					}
+
+					return 0, nil, nil
+				}
+
+				return sp + 1 + length, data[sp+1 : sp+1+length], nil
+			}
+		} else if !atEOF && len(data) < 10 {
+			return 0, nil, nil
+		}
+	}
+
+	// Newline termination.
+	return bufio.ScanLines(data, atEOF)
+}
+
+// Receive messages from a stream connection.
+func (i *syslogInput) receiveStream(conn net.Conn) {
+	defer i.receiveWg.Done()
+	defer func() {
+		i.mutex.Lock()
+		delete(i.conns, conn)
+		i.mutex.Unlock()
+
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxSyslogMessageSize+16)
+	scanner.Split(splitSyslogStream)
+
+	for scanner.Scan() {
+		i.receive(scanner.Bytes())
+	}
+
+	if err := scanner.Err(); err != nil && err != io.EOF && !i.isClosed() {
+		fmt.Fprintf(os.Stderr, "Failed to receive syslog messages on %s: %s\n", i, err)
+	}
+}
+
+// Test if the input has been closed.
+func (i *syslogInput) isClosed() bool {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return i.closed
+}
+
+func (i *syslogInput) Run(sink Sink) error {
+	i.mutex.Lock()
+	if i.running || i.closed {
+		i.mutex.Unlock()
+		return fmt.Errorf("syslog input already run")
+	}
+	i.running = true
+	i.receiveWg.Add(1)
+	i.mutex.Unlock()
+
+	defer close(i.done)
+
+	if i.conn != nil {
+		go i.receiveDatagrams()
+	} else {
+		go i.accept()
+	}
+
+	// Stop sinking once nothing is received anymore.
+	go func() {
+		i.receiveWg.Wait()
+		close(i.messages)
+	}()
+
+	for line := range i.messages {
+		sink(line)
+	}
+
+	if !i.isClosed() {
+		return fmt.Errorf("stopped receiving syslog messages on %s", i)
+	}
+
+	return nil
+}
+
+func (i *syslogInput) Close() error {
+	i.mutex.Lock()
+	if i.closed {
+		i.mutex.Unlock()
+		return nil
+	}
+	i.closed = true
+	running := i.running
+
+	var err error
+	if i.conn != nil {
+		err = i.conn.Close()
+
+		// Unlike stream listeners, datagram sockets do not remove their
+		// socket file.
+		if i.network == "unixgram" {
+			os.Remove(i.address)
+		}
+	} else {
+		err = i.listener.Close()
+	}
+
+	for conn := range i.conns {
+		conn.Close()
+	}
+	i.mutex.Unlock()
+
+	if running {
+		<-i.done
+	}
+
+	return err
+}
+
+func (i *syslogInput) String() string {
+	return fmt.Sprintf("%s://%s", i.network, i.address)
+}
+
+// New syslog input.
+//
+// Listens for RFC 5424 and RFC 3164 syslog messages on the address. The
+// network must be one of udp, tcp, unix and unixgram. Messages are forwarded
+// as lines prefixed by their tag, and up to 10240 messages are buffered while
+// sinking is blocked. Messages which cannot be parsed are forwarded as is.
+func NewSyslogInput(network, address string) (Input, error) {
+	i := &syslogInput{
+		network:  network,
+		address:  address,
+		messages: make(chan []byte, syslogBufferSize),
+		conns:    make(map[net.Conn]struct{}),
+		done:     make(chan struct{}),
+	}
+
+	// Remove stale sockets left behind by a previous run.
+	if network == "unix" || network == "unixgram" {
+		if fi, err := os.Lstat(address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+	}
+
+	var err error
+
+	switch network {
+	case "udp", "unixgram":
+		i.conn, err = net.ListenPacket(network, address)
+
+	case "tcp", "unix":
+		i.listener, err = net.Listen(network, address)
+
+	default:
+		return nil, fmt.Errorf("unsupported network: %s", network)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
@@ -0,0 +1,234 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Syslog message.
+type syslogMessage struct {
+	// Facility.
+	Facility int
+
+	// Severity.
+	Severity int
+
+	// Timestamp.
+	//
+	// Zero if not provided.
+	Timestamp time.Time
+
+	// Hostname.
+	Hostname string
+
+	// Application name or tag.
+	AppName string
+
+	// Process ID.
+	ProcId string
+
+	// Message ID.
+	MsgId string
+
+	// Message.
+	Message string
+}
+
+// Line representation of the message.
+//
+// The message is prefixed by the application name and process ID, if any,
+// like the local syslog daemon would.
+func (m *syslogMessage) line() []byte {
+	if m.AppName == "" {
+		return []byte(m.Message)
+	}
+
+	if m.ProcId != "" {
+		return []byte(fmt.Sprintf("%s[%s]: %s", m.AppName, m.ProcId, m.Message))
+	}
+
+	return []byte(fmt.Sprintf("%s: %s", m.AppName, m.Message))
+}
+
+// Default priority of messages without one, user.notice.
+const syslogDefaultPriority = 13
+
+// RFC 3164 timestamp layout.
+const rfc3164TimestampLayout = time.Stamp
+
+// Cut the next space-separated field off a string.
+func cutSyslogField(s string) (field, rest string) {
+	if i := strings.IndexByte(s, ' '); i != -1 {
+		return s[:i], s[i+1:]
+	}
+
+	return s, ""
+}
+
+// Nil value of a RFC 5424 field.
+func syslogNilValue(field string) string {
+	if field == "-" {
+		return ""
+	}
+
+	return field
+}
+
+// Parse a syslog message.
+//
+// Both RFC 5424 and RFC 3164 messages are supported. As RFC 3164 is merely a
+// description of common practice, anything not conforming to it is treated as
+// the message.
+func parseSyslogMessage(data []byte) (*syslogMessage, error) {
+	data = bytes.TrimRight(data, "\r\n\x00")
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty message")
+	}
+
+	m := &syslogMessage{}
+	s := string(data)
+
+	// Parse the priority.
+	priority := syslogDefaultPriority
+
+	if s[0] == '<' {
+		end := strings.IndexByte(s, '>')
+		if end < 2 || end > 4 {
+			return nil, fmt.Errorf("invalid priority")
+		}
+
+		p, err := strconv.Atoi(s[1:end])
+		if err != nil || p < 0 || p > 191 {
+			return nil, fmt.Errorf("invalid priority: %s", s[1:end])
+		}
+
+		priority = p
+		s = s[end+1:]
+	}
+
+	m.Facility = priority / 8
+	m.Severity = priority % 8
+
+	if strings.HasPrefix(s, "1 ") {
+		return m, parseRfc5424Message(m, s[2:])
+	}
+
+	parseRfc3164Message(m, s)
+	return m, nil
+}
+
+// Parse the remainder of a RFC 5424 message after the version.
+func parseRfc5424Message(m *syslogMessage, s string) error {
+	var timestamp string
+	timestamp, s = cutSyslogField(s)
+
+	if timestamp != "-" {
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %s", timestamp)
+		}
+		m.Timestamp = t
+	}
+
+	var field string
+	field, s = cutSyslogField(s)
+	m.Hostname = syslogNilValue(field)
+	field, s = cutSyslogField(s)
+	m.AppName = syslogNilValue(field)
+	field, s = cutSyslogField(s)
+	m.ProcId = syslogNilValue(field)
+	field, s = cutSyslogField(s)
+	m.MsgId = syslogNilValue(field)
+
+	// Skip the structured data, which is either nil or a sequence of
+	// bracketed elements in which parameter values may contain escaped
+	// closing brackets.
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	} else {
+		for strings.HasPrefix(s, "[") {
+			i := 1
+			quoted := false
+
+			for ; i < len(s); i++ {
+				c := s[i]
+
+				if quoted && c == '\\' {
+					i++
+				} else if c == '"' {
+					quoted = !quoted
+				} else if c == ']' && !quoted {
+					break
+				}
+			}
+
+			if i >= len(s) {
+				return fmt.Errorf("unterminated structured data")
+			}
+
+			s = s[i+1:]
+		}
+	}
+
+	s = strings.TrimPrefix(s, " ")
+	m.Message = strings.TrimPrefix(s, "\ufeff")
+
+	return nil
+}
+
+// Parse the remainder of a RFC 3164 message after the priority.
+//
+// The hostname is optional, as messages sent to the local syslog daemon
+// usually do not include it.
+func parseRfc3164Message(m *syslogMessage, s string) {
+	if len(s) < len(rfc3164TimestampLayout) {
+		m.Message = s
+		return
+	}
+
+	t, err := time.Parse(rfc3164TimestampLayout, s[:len(rfc3164TimestampLayout)])
+	if err != nil {
+		m.Message = s
+		return
+	}
+
+	now := time.Now()
+	m.Timestamp = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+
+	// The year is not included, so messages from the end of December
+	// received in January belong to the previous year.
+	if m.Timestamp.After(now.Add(24 * time.Hour)) {
+		m.Timestamp = m.Timestamp.AddDate(-1, 0, 0)
+	}
+
+	s = strings.TrimPrefix(s[len(rfc3164TimestampLayout):], " ")
+
+	// The hostname is present unless the next field is the tag.
+	if field, rest := cutSyslogField(s); !isRfc3164Tag(field) && rest != "" {
+		m.Hostname = field
+		s = rest
+	}
+
+	// Parse the tag, which may include the process ID.
+	if field, rest := cutSyslogField(s); isRfc3164Tag(field) {
+		tag := strings.TrimSuffix(field, ":")
+
+		if open := strings.IndexByte(tag, '['); open != -1 && strings.HasSuffix(tag, "]") {
+			m.ProcId = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+
+		m.AppName = tag
+		s = rest
+	}
+
+	m.Message = s
+}
+
+// Test if a RFC 3164 field is a tag.
+func isRfc3164Tag(field string) bool {
+	return len(field) > 1 && strings.HasSuffix(field, ":")
+}
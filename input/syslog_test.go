@@ -0,0 +1,157 @@
+package input
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSyslogMessage(t *testing.T) {
+	for _, tc := range []struct {
+		data     string
+		expected syslogMessage
+		line     string
+	}{
+		// RFC 5424.
+		{
+			`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application\]"] ` + "\ufeff" + `An application event log entry...`,
+			syslogMessage{
+				Facility:  20,
+				Severity:  5,
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "mymachine.example.com",
+				AppName:   "evntslog",
+				MsgId:     "ID47",
+				Message:   "An application event log entry...",
+			},
+			"evntslog: An application event log entry...",
+		},
+		{
+			"<14>1 - - app 123 - - hello\n",
+			syslogMessage{
+				Facility: 1,
+				Severity: 6,
+				AppName:  "app",
+				ProcId:   "123",
+				Message:  "hello",
+			},
+			"app[123]: hello",
+		},
+
+		// RFC 3164.
+		{
+			"<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8",
+			syslogMessage{
+				Facility: 4,
+				Severity: 2,
+				Hostname: "mymachine",
+				AppName:  "su",
+				Message:  "'su root' failed for lonvick on /dev/pts/8",
+			},
+			"su: 'su root' failed for lonvick on /dev/pts/8",
+		},
+		{
+			"<30>Feb  3 04:05:06 app[42]: started",
+			syslogMessage{
+				Facility: 3,
+				Severity: 6,
+				AppName:  "app",
+				ProcId:   "42",
+				Message:  "started",
+			},
+			"app[42]: started",
+		},
+		{
+			"just a message",
+			syslogMessage{
+				Facility: 1,
+				Severity: 5,
+				Message:  "just a message",
+			},
+			"just a message",
+		},
+	} {
+		m, err := parseSyslogMessage([]byte(tc.data))
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", tc.data, err)
+			continue
+		}
+
+		// RFC 3164 timestamps lack the year, so they are not compared.
+		if tc.expected.Timestamp.IsZero() {
+			m.Timestamp = time.Time{}
+		}
+
+		if !reflect.DeepEqual(*m, tc.expected) {
+			t.Errorf("Expected %q to parse to %+v, but got %+v", tc.data, tc.expected, *m)
+		}
+
+		if line := string(m.line()); line != tc.line {
+			t.Errorf("Expected %q to produce line %q, but got %q", tc.data, tc.line, line)
+		}
+	}
+
+	if _, err := parseSyslogMessage([]byte("<999>1 - - - - - - x")); err == nil {
+		t.Errorf("Expected invalid priority to fail parsing")
+	}
+}
+
+func TestSplitSyslogStream(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("11 <13>1 - - a<13>first\n<13>second\n5 third"))
+	scanner.Split(splitSyslogStream)
+
+	var messages []string
+	for scanner.Scan() {
+		messages = append(messages, scanner.Text())
+	}
+
+	expected := []string{"<13>1 - - a", "<13>first", "<13>second", "third"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected messages %q, but got %q", expected, messages)
+	}
+}
+
+func TestSyslogInput(t *testing.T) {
+	i, err := NewSyslogInput("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to create syslog input: %s", err)
+	}
+
+	lines := make(chan string, 10)
+	runErr := make(chan error, 1)
+
+	go func() {
+		runErr <- i.Run(func(line []byte) {
+			lines <- string(line)
+		})
+	}()
+
+	conn, err := net.Dial("udp", i.(*syslogInput).conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("<13>Oct 11 22:14:15 app: hello\n")); err != nil {
+		t.Fatalf("Failed to send message: %s", err)
+	}
+
+	select {
+	case line := <-lines:
+		if line != "app: hello" {
+			t.Errorf("Expected line %q, but got %q", "app: hello", line)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for message")
+	}
+
+	i.Close()
+
+	if err = <-runErr; err != nil {
+		t.Errorf("Unexpected error from running: %s", err)
+	}
+}
@@ -0,0 +1,483 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Maximum length of a line read from a tailed file.
+//
+// Longer lines are split.
+const maxTailLineLength = 64 * 1024
+
+// Tailed file state.
+//
+// Persisted, so tailing can resume where it left off.
+type tailFileState struct {
+	Path   string `json:"path"`
+	Dev    uint64 `json:"dev"`
+	Ino    uint64 `json:"ino"`
+	Offset int64  `json:"offset"`
+}
+
+// Tailed file.
+type tailFile struct {
+	path    string
+	f       *os.File
+	dev     uint64
+	ino     uint64
+	offset  int64
+	partial []byte
+}
+
+// Read position.
+func (tf *tailFile) position() int64 {
+	return tf.offset + int64(len(tf.partial))
+}
+
+// Sink a line.
+func (tf *tailFile) sinkLine(line []byte, sink Sink) {
+	tf.offset += int64(len(line))
+
+	line = bytes.TrimRight(line, "\n")
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+
+	sink(line)
+}
+
+// Read lines written to the file since the last read.
+//
+// Returns true if anything was read.
+func (tf *tailFile) read(sink Sink) bool {
+	buf := make([]byte, 32*1024)
+	read := false
+
+	for {
+		n, err := tf.f.Read(buf)
+
+		if n > 0 {
+			read = true
+			data := append(tf.partial, buf[:n]...)
+			tf.partial = nil
+
+			for len(data) > 0 {
+				nl := bytes.IndexByte(data, '\n')
+				if nl == -1 {
+					if len(data) >= maxTailLineLength {
+						tf.sinkLine(data, sink)
+					} else {
+						tf.partial = append([]byte(nil), data...)
+					}
+					break
+				}
+
+				tf.sinkLine(data[:nl+1], sink)
+				data = data[nl+1:]
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(os.Stderr, "Failed to read %s: %s\n", tf.path, err)
+			}
+
+			return read
+		}
+	}
+}
+
+// Finish reading the file.
+//
+// Reads the remaining lines, including any incomplete last line, and closes
+// the file.
+func (tf *tailFile) finish(sink Sink) {
+	tf.read(sink)
+
+	if len(tf.partial) > 0 {
+		partial := tf.partial
+		tf.partial = nil
+		tf.sinkLine(partial, sink)
+	}
+
+	tf.f.Close()
+}
+
+// Tail input.
+type tailInput struct {
+	patterns  []string
+	statePath string
+	interval  time.Duration
+	files     map[string]*tailFile
+	state     []tailFileState
+	resumed   bool
+	stop      chan struct{}
+	done      chan struct{}
+	mutex     sync.Mutex
+	running   bool
+	closed    bool
+}
+
+// Saved state of a file.
+//
+// Files are identified by device and inode, so files renamed since the state
+// was saved are found under their new path. Where the identity of files is
+// not available, files are identified by path instead.
+func (t *tailInput) savedState(path string, id tailFileId) (tailFileState, bool) {
+	for _, s := range t.state {
+		if s.Dev == id.dev && s.Ino == id.ino && (id != (tailFileId{}) || s.Path == path) {
+			return s, true
+		}
+	}
+
+	return tailFileState{}, false
+}
+
+// Open a file for tailing.
+//
+// Resumes from the saved offset if the file was followed when the state was
+// saved. Otherwise, the file is read from the end or the beginning.
+func (t *tailInput) open(path string, fromEnd bool) (*tailFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	tf := &tailFile{
+		path: path,
+		f:    f,
+	}
+	tf.dev, tf.ino = fileId(fi)
+
+	if s, ok := t.savedState(path, tailFileId{tf.dev, tf.ino}); ok && s.Offset <= fi.Size() {
+		tf.offset = s.Offset
+	} else if fromEnd {
+		tf.offset = fi.Size()
+	}
+
+	if _, err = f.Seek(tf.offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return tf, nil
+}
+
+// File identity.
+type tailFileId struct {
+	dev uint64
+	ino uint64
+}
+
+// Expand the patterns into the paths of regular files.
+//
+// Returns the identities of the files by path.
+func (t *tailInput) expand() map[string]tailFileId {
+	paths := make(map[string]tailFileId)
+
+	for _, pattern := range t.patterns {
+		matches, _ := filepath.Glob(pattern)
+
+		for _, path := range matches {
+			if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+				dev, ino := fileId(fi)
+				paths[path] = tailFileId{dev, ino}
+			}
+		}
+	}
+
+	return paths
+}
+
+// Find a regular file by its identity in a directory.
+func findFile(dir string, id tailFileId) (string, bool) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for _, fi := range entries {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+
+		if dev, ino := fileId(fi); (tailFileId{dev, ino}) == id {
+			return filepath.Join(dir, fi.Name()), true
+		}
+	}
+
+	return "", false
+}
+
+// Finish reading files rotated away since the state was saved.
+//
+// Files followed when the state was saved which no longer match the patterns
+// are looked for by their identity in the directory they were in, and read
+// from the saved offset to the end. Returns true if any file was read.
+func (t *tailInput) finishRotated(sink Sink, paths map[string]tailFileId) bool {
+	matched := make(map[tailFileId]bool, len(paths))
+	for _, id := range paths {
+		matched[id] = true
+	}
+
+	finished := false
+
+	for _, s := range t.state {
+		id := tailFileId{s.Dev, s.Ino}
+		if id == (tailFileId{}) || matched[id] {
+			continue
+		}
+
+		path, ok := findFile(filepath.Dir(s.Path), id)
+		if !ok {
+			continue
+		}
+
+		tf, err := t.open(path, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open %s: %s\n", path, err)
+			continue
+		}
+
+		tf.finish(sink)
+		finished = true
+	}
+
+	return finished
+}
+
+// Poll the files.
+//
+// Follows files across rotation by renaming and truncation. Returns true if
+// anything was read.
+func (t *tailInput) poll(sink Sink, initial bool) bool {
+	paths := t.expand()
+	changed := false
+
+	// Lines of files rotated away while not running come before those of the
+	// files replacing them.
+	if initial && t.resumed && t.finishRotated(sink, paths) {
+		changed = true
+	}
+
+	// Handle files which have been replaced or removed. Files renamed to a
+	// path which still matches are followed under the new path, while the
+	// rest are read to the end and no longer followed.
+	for path, tf := range t.files {
+		id := tailFileId{tf.dev, tf.ino}
+		if pathId, ok := paths[path]; ok && pathId == id {
+			continue
+		}
+
+		delete(t.files, path)
+		changed = true
+
+		// Renamed files are read right away, so their lines come before those
+		// of any file replacing them.
+		renamed := false
+		for newPath, newId := range paths {
+			if _, followed := t.files[newPath]; !followed && newId == id {
+				tf.path = newPath
+				t.files[newPath] = tf
+				tf.read(sink)
+				renamed = true
+				break
+			}
+		}
+
+		if !renamed {
+			tf.finish(sink)
+		}
+	}
+
+	// Follow new files. Without any saved state, files present when first
+	// starting are read from the end, while files appearing later, such as
+	// files replacing rotated files, are read from the beginning. With saved
+	// state, files not followed before are read from the beginning.
+	sortedPaths := make([]string, 0, len(paths))
+	for path := range paths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+
+	for _, path := range sortedPaths {
+		tf, ok := t.files[path]
+
+		if !ok {
+			var err error
+			if tf, err = t.open(path, initial && !t.resumed); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to open %s: %s\n", path, err)
+				continue
+			}
+
+			t.files[path] = tf
+			changed = true
+		}
+
+		// Detect truncation.
+		if fi, err := tf.f.Stat(); err == nil && fi.Size() < tf.position() {
+			if _, err = tf.f.Seek(0, io.SeekStart); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to rewind %s: %s\n", path, err)
+				continue
+			}
+
+			tf.offset = 0
+			tf.partial = nil
+			changed = true
+		}
+
+		if tf.read(sink) {
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// Save the state.
+func (t *tailInput) saveState() error {
+	t.state = make([]tailFileState, 0, len(t.files))
+
+	for path, tf := range t.files {
+		t.state = append(t.state, tailFileState{
+			Path:   path,
+			Dev:    tf.dev,
+			Ino:    tf.ino,
+			Offset: tf.offset,
+		})
+	}
+
+	sort.Slice(t.state, func(i, j int) bool {
+		return t.state[i].Path < t.state[j].Path
+	})
+
+	if t.statePath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(t.state)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(t.statePath), "."+filepath.Base(t.statePath))
+	if err = ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, t.statePath)
+}
+
+func (t *tailInput) Run(sink Sink) error {
+	t.mutex.Lock()
+	if t.running || t.closed {
+		t.mutex.Unlock()
+		return fmt.Errorf("tail input already run")
+	}
+	t.running = true
+	t.mutex.Unlock()
+
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	initial := true
+
+	for {
+		if t.poll(sink, initial) {
+			if err := t.saveState(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save tail state: %s\n", err)
+			}
+		}
+		initial = false
+
+		select {
+		case <-ticker.C:
+
+		case <-t.stop:
+			for _, tf := range t.files {
+				tf.f.Close()
+			}
+
+			return t.saveState()
+		}
+	}
+}
+
+func (t *tailInput) Close() error {
+	t.mutex.Lock()
+	if t.closed {
+		t.mutex.Unlock()
+		return nil
+	}
+	t.closed = true
+	running := t.running
+	t.mutex.Unlock()
+
+	close(t.stop)
+
+	if running {
+		<-t.done
+	}
+
+	return nil
+}
+
+// New tail input.
+//
+// Follows the files matching the glob patterns like tail -F, polling them at
+// the interval. Files are followed across rotation by renaming or truncation.
+// If the state path is not empty, the read offsets are saved in a file at the
+// path, so tailing resumes where it left off.
+func NewTailInput(patterns []string, statePath string, interval time.Duration) (Input, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no patterns provided")
+	}
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %s", pattern, err)
+		}
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+
+	t := &tailInput{
+		patterns:  patterns,
+		statePath: statePath,
+		interval:  interval,
+		files:     make(map[string]*tailFile),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	if statePath != "" {
+		data, err := ioutil.ReadFile(statePath)
+		if err == nil {
+			if err = json.Unmarshal(data, &t.state); err != nil {
+				return nil, fmt.Errorf("invalid tail state in %s: %s", statePath, err)
+			}
+			t.resumed = true
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read tail state: %s", err)
+		}
+	}
+
+	return t, nil
+}
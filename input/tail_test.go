@@ -0,0 +1,143 @@
+package input
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Append data to a file.
+func appendFile(t *testing.T, path, data string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(data); err != nil {
+		t.Fatalf("Failed to write to %s: %s", path, err)
+	}
+}
+
+// Poll a tail input and return the lines sunk.
+func pollLines(ti *tailInput, initial bool) []string {
+	var lines []string
+	ti.poll(func(line []byte) {
+		lines = append(lines, string(line))
+	}, initial)
+	ti.saveState()
+	return lines
+}
+
+func TestTailInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coyote-tail")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "app.log")
+	statePath := filepath.Join(dir, "state.json")
+	appendFile(t, logPath, "old\n")
+
+	newTail := func() *tailInput {
+		i, err := NewTailInput([]string{filepath.Join(dir, "*.log*")}, statePath, time.Second)
+		if err != nil {
+			t.Fatalf("Failed to create tail input: %s", err)
+		}
+		return i.(*tailInput)
+	}
+
+	expect := func(actual []string, expected ...string) {
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected lines %q, but got %q", expected, actual)
+		}
+	}
+
+	// Existing content is skipped when starting without state.
+	ti := newTail()
+	expect(pollLines(ti, true))
+
+	// Appended lines are read, while incomplete lines are held back.
+	appendFile(t, logPath, "a\nb\r\nc")
+	expect(pollLines(ti, false), "a", "b")
+	appendFile(t, logPath, "\n")
+	expect(pollLines(ti, false), "c")
+
+	// Rotation by renaming continues reading the renamed file and reads the
+	// new file from the beginning.
+	appendFile(t, logPath, "d\n")
+	if err = os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatalf("Failed to rotate: %s", err)
+	}
+	appendFile(t, logPath+".1", "e\n")
+	appendFile(t, logPath, "f\n")
+	expect(pollLines(ti, false), "d", "e", "f")
+
+	// Truncation starts reading from the beginning.
+	if err = os.Truncate(logPath+".1", 0); err != nil {
+		t.Fatalf("Failed to truncate: %s", err)
+	}
+	appendFile(t, logPath+".1", "g\n")
+	expect(pollLines(ti, false), "g")
+
+	// Restarting resumes from the saved offsets.
+	ti.Close()
+	appendFile(t, logPath, "h\n")
+
+	ti = newTail()
+	expect(pollLines(ti, true), "h")
+}
+
+func TestTailInputResumeRotated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coyote-tail")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "app.log")
+	statePath := filepath.Join(dir, "state.json")
+	appendFile(t, logPath, "old\n")
+
+	newTail := func() *tailInput {
+		i, err := NewTailInput([]string{logPath}, statePath, time.Second)
+		if err != nil {
+			t.Fatalf("Failed to create tail input: %s", err)
+		}
+		return i.(*tailInput)
+	}
+
+	expect := func(actual []string, expected ...string) {
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected lines %q, but got %q", expected, actual)
+		}
+	}
+
+	ti := newTail()
+	expect(pollLines(ti, true))
+	appendFile(t, logPath, "a\n")
+	expect(pollLines(ti, false), "a")
+	ti.Close()
+
+	// Files rotated away while stopped are found by their identity and read
+	// to the end before the files replacing them are read from the
+	// beginning.
+	appendFile(t, logPath, "b\n")
+	if err = os.Rename(logPath, logPath+".1"); err != nil {
+		t.Fatalf("Failed to rotate: %s", err)
+	}
+	appendFile(t, logPath+".1", "c\n")
+	appendFile(t, logPath, "d\n")
+
+	ti = newTail()
+	expect(pollLines(ti, true), "b", "c", "d")
+	ti.Close()
+
+	// Restarting again duplicates nothing.
+	ti = newTail()
+	expect(pollLines(ti, true))
+}
@@ -2,6 +2,8 @@ package output
 
 import (
 	"github.com/nickbruun/coyote/utils"
+	"sync/atomic"
+	"time"
 )
 
 // Draining output sink function.
@@ -15,24 +17,45 @@ type drainingOutputClose func()
 // Output which drains lines while writing previous lines to avoid blocking. If
 // sinking fails, it will be retried.
 type drainingOutput struct {
-	lineCh chan []byte
-	done   chan struct{}
+	lineCh  chan []byte
+	done    chan struct{}
+	pending int64
 }
 
 func (o *drainingOutput) Sink(line []byte) {
+	atomic.AddInt64(&o.pending, 1)
 	o.lineCh <- line
 }
 
 func (o *drainingOutput) Close() error {
+	return o.CloseTimeout(0)
+}
+
+func (o *drainingOutput) CloseTimeout(timeout time.Duration) error {
 	// Note: not strictly atomic, but we'll survive for now.
 	if o.lineCh != nil {
 		close(o.lineCh)
 		o.lineCh = nil
 	}
 
-	<-o.done
+	if timeout == 0 {
+		<-o.done
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-o.done:
+		return nil
 
-	return nil
+	case <-timer.C:
+		return &DrainTimeoutError{
+			Abandoned: int(atomic.LoadInt64(&o.pending)),
+			Timeout:   timeout,
+		}
+	}
 }
 
 // New draining output.
@@ -40,15 +63,33 @@ func (o *drainingOutput) Close() error {
 // The buffer size is the maximum number of lines buffered at once. If the
 // buffer is overflown, older lines will be discarded.
 func newDrainingOutput(bufferSize int, oSink drainingOutputSink, oClose drainingOutputClose) (Output, error) {
-	lineCh := make(chan []byte, 1024)
-	done := make(chan struct{})
+	o := &drainingOutput{
+		lineCh: make(chan []byte, 1024),
+		done:   make(chan struct{}),
+	}
+	lineCh := o.lineCh
 
 	go func() {
 		lines := utils.NewByteSliceBuffer(bufferSize)
 
+		// Add a line to the buffer, accounting for any line discarded.
+		add := func(l []byte) {
+			if lines.Full() {
+				atomic.AddInt64(&o.pending, -1)
+			}
+
+			lines.Add(l)
+		}
+
+		// Sink lines, accounting for them no longer being pending.
+		sink := func(sinkLines [][]byte) {
+			oSink(sinkLines)
+			atomic.AddInt64(&o.pending, -int64(len(sinkLines)))
+		}
+
 		for l := range lineCh {
 			// Drain up until the mark of the buffer size.
-			lines.Add(l)
+			add(l)
 
 			drained := false
 			for !drained && !lines.Full() {
@@ -57,7 +98,7 @@ func newDrainingOutput(bufferSize int, oSink drainingOutputSink, oClose draining
 					if l == nil {
 						drained = true
 					} else {
-						lines.Add(l)
+						add(l)
 					}
 
 				default:
@@ -74,7 +115,7 @@ func newDrainingOutput(bufferSize int, oSink drainingOutputSink, oClose draining
 			sinkDone := make(chan struct{})
 
 			go func() {
-				oSink(sinkLines)
+				sink(sinkLines)
 				close(sinkDone)
 			}()
 
@@ -85,8 +126,9 @@ func newDrainingOutput(bufferSize int, oSink drainingOutputSink, oClose draining
 					if l == nil {
 						<-sinkDone
 						done = true
+					} else {
+						add(l)
 					}
-					lines.Add(l)
 
 				case <-sinkDone:
 					done = true
@@ -96,15 +138,12 @@ func newDrainingOutput(bufferSize int, oSink drainingOutputSink, oClose draining
 
 		// Sink any lines left.
 		if !lines.Empty() {
-			oSink(lines.Drain())
+			sink(lines.Drain())
 		}
 
 		oClose()
-		close(done)
+		close(o.done)
 	}()
 
-	return &drainingOutput{
-		lineCh: lineCh,
-		done:   done,
-	}, nil
+	return o, nil
 }
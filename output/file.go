@@ -5,9 +5,19 @@ import (
 	"os"
 )
 
+// File output.
+type fileOutput struct {
+	*drainingOutput
+	terminal bool
+}
+
+func (o *fileOutput) IsTerminal() bool {
+	return o.terminal
+}
+
 // New file output.
 func newFileOutput(f *os.File) (Output, error) {
-	return newDrainingOutput(10240, func(lines [][]byte) error {
+	o, err := newDrainingOutput(10240, func(lines [][]byte) error {
 		_, err := f.Write(bytes.Join(append(lines, []byte{}), lineEnding))
 		return err
 	}, func() {
@@ -17,4 +27,17 @@ func newFileOutput(f *os.File) (Output, error) {
 			f.Close()
 		}
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	terminal := false
+	if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		terminal = true
+	}
+
+	return &fileOutput{
+		drainingOutput: o.(*drainingOutput),
+		terminal:       terminal,
+	}, nil
 }
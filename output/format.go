@@ -0,0 +1,273 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Default layout of timestamps.
+const DefaultTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Line format.
+type Format int
+
+const (
+	// Lines as is.
+	FormatRaw Format = iota
+
+	// Lines prefixed by a timestamp.
+	FormatTimestamped
+
+	// Lines as logfmt key=value pairs.
+	FormatLogfmt
+
+	// Lines as JSON objects.
+	FormatJson
+)
+
+// Parse a line format name.
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "raw":
+		return FormatRaw, nil
+	case "timestamped":
+		return FormatTimestamped, nil
+	case "logfmt":
+		return FormatLogfmt, nil
+	case "json":
+		return FormatJson, nil
+	default:
+		return 0, fmt.Errorf("invalid format: %s", name)
+	}
+}
+
+// Format configuration.
+type FormatConfig struct {
+	// Format.
+	Format Format
+
+	// Layout of timestamps as accepted by time.Time.Format. Defaults to
+	// DefaultTimeLayout.
+	TimeLayout string
+
+	// Format timestamps in UTC rather than local time.
+	UTC bool
+}
+
+// Parse a format configuration from URL query parameters.
+//
+// The format is given by format, the layout of timestamps by time-layout and
+// UTC timestamps by utc=true. The parameters are removed from the query.
+func parseFormatQuery(query url.Values) (config FormatConfig, err error) {
+	if name := query.Get("format"); name != "" {
+		if config.Format, err = ParseFormat(name); err != nil {
+			return
+		}
+	}
+
+	config.TimeLayout = query.Get("time-layout")
+
+	if v := query.Get("utc"); v != "" {
+		if config.UTC, err = strconv.ParseBool(v); err != nil {
+			return config, fmt.Errorf("invalid value for utc: %s", v)
+		}
+	}
+
+	query.Del("format")
+	query.Del("time-layout")
+	query.Del("utc")
+
+	return
+}
+
+// JSON formatted record.
+type jsonRecord struct {
+	Ts      string                 `json:"ts"`
+	Stream  string                 `json:"stream,omitempty"`
+	Host    string                 `json:"host,omitempty"`
+	Process string                 `json:"process,omitempty"`
+	Cmd     []string               `json:"cmd,omitempty"`
+	Level   string                 `json:"level,omitempty"`
+	Msg     string                 `json:"msg"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Formatted output.
+//
+// Formats records before sinking them as lines to an underlying output.
+type formattedOutput struct {
+	output Output
+	config FormatConfig
+	host   string
+}
+
+// Append a logfmt value.
+func appendLogfmtValue(b []byte, v string) []byte {
+	quote := v == "" || !utf8.ValidString(v)
+	for _, c := range v {
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			quote = true
+			break
+		}
+	}
+
+	if quote {
+		return strconv.AppendQuote(b, v)
+	}
+
+	return append(b, v...)
+}
+
+// Format a record.
+func (o *formattedOutput) format(r Record) []byte {
+	if o.config.Format == FormatRaw {
+		return r.Line
+	}
+
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if o.config.UTC {
+		t = t.UTC()
+	}
+	ts := t.Format(o.config.TimeLayout)
+
+	switch o.config.Format {
+	case FormatTimestamped:
+		l := make([]byte, 0, len(ts)+1+len(r.Line))
+		l = append(l, ts...)
+		l = append(l, ' ')
+		return append(l, r.Line...)
+
+	case FormatLogfmt:
+		l := append(make([]byte, 0, 64+len(r.Line)), "ts="...)
+		l = appendLogfmtValue(l, ts)
+
+		for _, kv := range [][2]string{
+			{"stream", r.Stream},
+			{"host", o.host},
+			{"process", r.Process},
+			{"cmd", strings.Join(r.Cmd, " ")},
+			{"level", r.Level},
+		} {
+			if kv[1] != "" {
+				l = append(l, ' ')
+				l = append(l, kv[0]...)
+				l = append(l, '=')
+				l = appendLogfmtValue(l, kv[1])
+			}
+		}
+
+		l = append(l, " msg="...)
+		l = appendLogfmtValue(l, r.Msg())
+
+		// Fields are appended in order of their keys, with values which are
+		// not strings as JSON.
+		keys := make([]string, 0, len(r.Fields))
+		for k := range r.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			v, ok := r.Fields[k].(string)
+			if !ok {
+				encoded, _ := json.Marshal(r.Fields[k])
+				v = string(encoded)
+			}
+
+			l = append(l, ' ')
+			l = appendLogfmtValue(l, k)
+			l = append(l, '=')
+			l = appendLogfmtValue(l, v)
+		}
+
+		return l
+
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+
+		encoder.Encode(jsonRecord{
+			Ts:      ts,
+			Stream:  r.Stream,
+			Host:    o.host,
+			Process: r.Process,
+			Cmd:     r.Cmd,
+			Level:   r.Level,
+			Msg:     r.Msg(),
+			Fields:  r.Fields,
+		})
+
+		return bytes.TrimSuffix(buf.Bytes(), lineEnding)
+	}
+}
+
+func (o *formattedOutput) Sink(line []byte) {
+	o.SinkRecord(Record{Time: time.Now(), Line: line})
+}
+
+func (o *formattedOutput) SinkRecord(r Record) {
+	r.Line = o.format(r)
+	SinkRecord(o.output, r)
+}
+
+func (o *formattedOutput) Close() error {
+	return o.output.Close()
+}
+
+func (o *formattedOutput) CloseTimeout(timeout time.Duration) error {
+	return CloseTimeout(o.output, timeout)
+}
+
+func (o *formattedOutput) IsTerminal() bool {
+	return IsTerminal(o.output)
+}
+
+func (o *formattedOutput) IncludesProcess() bool {
+	return o.config.Format == FormatLogfmt || o.config.Format == FormatJson
+}
+
+// New formatted output.
+//
+// Lines are formatted before being sunk to the output, and so before any
+// framing of the output itself, such as the token of token-based TCP
+// outputs. Formats include the time of the line, and logfmt and JSON lines
+// also the stream, the host name, the process, the command and the level of
+// the line, for example:
+//
+//	2006-01-02T15:04:05.000Z listening on :8080
+//	ts=2006-01-02T15:04:05.000Z stream=stdout host=web1 cmd="myapp -v" msg="listening on :8080"
+//	{"ts":"2006-01-02T15:04:05.000Z","stream":"stdout","host":"web1","cmd":["myapp","-v"],"msg":"listening on :8080"}
+//
+// The stream, process, command and level are omitted for lines without them.
+// For lines parsed by a JSON parsing output, the message replaces the line,
+// and the remaining fields follow it in logfmt lines and are included as
+// fields in JSON lines. The output is returned as is for the raw format.
+func NewFormattedOutput(o Output, config FormatConfig) Output {
+	if config.Format == FormatRaw {
+		return o
+	}
+
+	if config.TimeLayout == "" {
+		config.TimeLayout = DefaultTimeLayout
+	}
+
+	host, _ := os.Hostname()
+
+	return &formattedOutput{
+		output: o,
+		config: config,
+		host:   host,
+	}
+}
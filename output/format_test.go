@@ -0,0 +1,72 @@
+package output
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFormattedOutput(t *testing.T) {
+	host, _ := os.Hostname()
+	r := Record{
+		Time:   time.Date(2016, 1, 2, 3, 4, 5, 6000000, time.UTC),
+		Stream: "stderr",
+		Cmd:    []string{"myapp", "-v"},
+		Line:   []byte(`say "hi"`),
+	}
+
+	for _, c := range []struct {
+		config   FormatConfig
+		expected string
+	}{
+		{FormatConfig{Format: FormatRaw}, `say "hi"`},
+		{FormatConfig{Format: FormatTimestamped, UTC: true}, `2016-01-02T03:04:05.006Z say "hi"`},
+		{FormatConfig{Format: FormatTimestamped, TimeLayout: "15:04:05", UTC: true}, `03:04:05 say "hi"`},
+		{FormatConfig{Format: FormatLogfmt, UTC: true}, `ts=2016-01-02T03:04:05.006Z stream=stderr host=` + string(appendLogfmtValue(nil, host)) + ` cmd="myapp -v" msg="say \"hi\""`},
+		{FormatConfig{Format: FormatJson, UTC: true}, `{"ts":"2016-01-02T03:04:05.006Z","stream":"stderr","host":"` + host + `","cmd":["myapp","-v"],"msg":"say \"hi\""}`},
+	} {
+		o := &recordingOutput{}
+		SinkRecord(NewFormattedOutput(o, c.config), r)
+
+		if len(o.lines) != 1 || o.lines[0] != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, o.lines)
+		}
+	}
+
+	r.Process = "web"
+
+	for _, c := range []struct {
+		config   FormatConfig
+		expected string
+	}{
+		{FormatConfig{Format: FormatLogfmt, UTC: true}, `ts=2016-01-02T03:04:05.006Z stream=stderr host=` + string(appendLogfmtValue(nil, host)) + ` process=web cmd="myapp -v" msg="say \"hi\""`},
+		{FormatConfig{Format: FormatJson, UTC: true}, `{"ts":"2016-01-02T03:04:05.006Z","stream":"stderr","host":"` + host + `","process":"web","cmd":["myapp","-v"],"msg":"say \"hi\""}`},
+	} {
+		o := &recordingOutput{}
+		f := NewFormattedOutput(o, c.config)
+		SinkRecord(f, r)
+
+		if !IncludesProcess(f) {
+			t.Errorf("expected format %d to include the process", c.config.Format)
+		}
+		if len(o.lines) != 1 || o.lines[0] != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, o.lines)
+		}
+	}
+}
+
+func TestOpenFormat(t *testing.T) {
+	if _, err := Open("stdout:?format=bogus"); err == nil {
+		t.Errorf("expected error opening output with invalid format")
+	}
+
+	o, err := Open("syslog:?format=json&tag=test")
+	if err != nil {
+		t.Fatalf("unexpected error opening output: %s", err)
+	}
+	defer o.Close()
+
+	if _, ok := o.(*formattedOutput); !ok {
+		t.Errorf("expected formatted output, got %T", o)
+	}
+}
@@ -0,0 +1,225 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Default keys of levels in JSON lines.
+var DefaultJsonLevelKeys = []string{"level", "severity", "lvl"}
+
+// Default keys of messages in JSON lines.
+var DefaultJsonMessageKeys = []string{"msg", "message"}
+
+// Default keys of timestamps in JSON lines.
+var DefaultJsonTimeKeys = []string{"time", "ts", "timestamp", "@timestamp"}
+
+// JSON parsing configuration.
+//
+// For each of the level, message and time, the first key present in an
+// object is extracted. Nil keys default to the default keys.
+type JsonParseConfig struct {
+	// Keys of the level.
+	LevelKeys []string
+
+	// Keys of the message.
+	MessageKeys []string
+
+	// Keys of the timestamp.
+	TimeKeys []string
+}
+
+// JSON parser.
+//
+// Parses lines which are JSON objects into records.
+type JsonParser struct {
+	config JsonParseConfig
+}
+
+// Format a JSON value as a string.
+func jsonString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// Nanoseconds per unit of a numeric JSON timestamp.
+//
+// The unit is inferred from the magnitude of the timestamp, which is taken to
+// be seconds, milliseconds, microseconds or nanoseconds since the epoch,
+// whichever is within a few thousand years of it.
+func jsonTimeUnit(abs float64) int64 {
+	switch {
+	case abs < 1e11:
+		return int64(time.Second)
+	case abs < 1e14:
+		return int64(time.Millisecond)
+	case abs < 1e17:
+		return int64(time.Microsecond)
+	default:
+		return int64(time.Nanosecond)
+	}
+}
+
+// Parse a JSON timestamp.
+//
+// Timestamps are either RFC 3339 strings, or numbers of seconds,
+// milliseconds, microseconds or nanoseconds since the epoch. See
+// jsonTimeUnit.
+func parseJsonTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			abs := math.Abs(float64(n))
+			unit := jsonTimeUnit(abs)
+			perSecond := int64(time.Second) / unit
+			return time.Unix(n/perSecond, n%perSecond*unit), nil
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		f = f * float64(jsonTimeUnit(math.Abs(f))) / float64(time.Second)
+		if math.IsNaN(f) || math.Abs(f) >= 1e18 {
+			return time.Time{}, fmt.Errorf("timestamp out of range")
+		}
+
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp")
+	}
+}
+
+// Extract the key and value of the first key present from fields.
+func extractJsonField(fields map[string]interface{}, keys []string) (string, interface{}, bool) {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			delete(fields, k)
+			return k, v, true
+		}
+	}
+
+	return "", nil, false
+}
+
+// Parse the line of a record.
+//
+// If the line is a JSON object, the level, message and timestamp of the
+// record are extracted from the object, and the remaining properties of the
+// object set as its fields. Values which cannot be extracted are left among
+// the fields. Other lines, including malformed JSON, are left as is. The line
+// itself is never changed, so outputs which do not use records receive lines
+// unchanged.
+func (p *JsonParser) Parse(r *Record) {
+	trimmed := bytes.TrimSpace(r.Line)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil || decoder.More() {
+		return
+	}
+
+	if k, v, ok := extractJsonField(fields, p.config.LevelKeys); ok {
+		if r.Level, ok = jsonString(v); !ok {
+			fields[k] = v
+		}
+	}
+
+	if k, v, ok := extractJsonField(fields, p.config.MessageKeys); ok {
+		if r.Message, ok = jsonString(v); !ok {
+			fields[k] = v
+		}
+	}
+
+	if k, v, ok := extractJsonField(fields, p.config.TimeKeys); ok {
+		if t, err := parseJsonTime(v); err == nil {
+			r.Time = t
+		} else {
+			fields[k] = v
+		}
+	}
+
+	r.Fields = fields
+}
+
+// New JSON parser.
+func NewJsonParser(config JsonParseConfig) *JsonParser {
+	if config.LevelKeys == nil {
+		config.LevelKeys = DefaultJsonLevelKeys
+	}
+	if config.MessageKeys == nil {
+		config.MessageKeys = DefaultJsonMessageKeys
+	}
+	if config.TimeKeys == nil {
+		config.TimeKeys = DefaultJsonTimeKeys
+	}
+
+	return &JsonParser{config: config}
+}
+
+// JSON parsing output.
+//
+// Parses lines before sinking them to an underlying output.
+type jsonParsingOutput struct {
+	output Output
+	parser *JsonParser
+}
+
+func (o *jsonParsingOutput) Sink(line []byte) {
+	o.SinkRecord(Record{Time: time.Now(), Line: line})
+}
+
+func (o *jsonParsingOutput) SinkRecord(r Record) {
+	o.parser.Parse(&r)
+	SinkRecord(o.output, r)
+}
+
+func (o *jsonParsingOutput) Close() error {
+	return o.output.Close()
+}
+
+func (o *jsonParsingOutput) CloseTimeout(timeout time.Duration) error {
+	return CloseTimeout(o.output, timeout)
+}
+
+func (o *jsonParsingOutput) IsTerminal() bool {
+	return IsTerminal(o.output)
+}
+
+func (o *jsonParsingOutput) IncludesProcess() bool {
+	return IncludesProcess(o.output)
+}
+
+// New JSON parsing output.
+//
+// Lines sunk to the output are parsed by the parser before being sunk to the
+// underlying output.
+func NewJsonParsingOutput(o Output, parser *JsonParser) Output {
+	return &jsonParsingOutput{
+		output: o,
+		parser: parser,
+	}
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"encoding/json"
+	"log/syslog"
+	"testing"
+	"time"
+)
+
+func TestJsonParser(t *testing.T) {
+	p := NewJsonParser(JsonParseConfig{})
+
+	r := Record{Line: []byte(`{"lvl":"warn","message":"disk full","ts":1451703845123,"disk":"/dev/sda"}`)}
+	p.Parse(&r)
+
+	if r.Level != "warn" || r.Message != "disk full" || r.Msg() != "disk full" {
+		t.Errorf("unexpected level and message: %q, %q", r.Level, r.Message)
+	}
+	if expected := time.Date(2016, 1, 2, 3, 4, 5, 123000000, time.UTC); !r.Time.Equal(expected) {
+		t.Errorf("expected time %s, got %s", expected, r.Time)
+	}
+	if len(r.Fields) != 1 || r.Fields["disk"] != "/dev/sda" {
+		t.Errorf("unexpected fields: %v", r.Fields)
+	}
+
+	for _, line := range []string{"plain", `{"msg":`, `{"msg":"a"} trailing`, `["msg"]`} {
+		r := Record{Line: []byte(line)}
+		p.Parse(&r)
+
+		if r.Fields != nil || r.Level != "" || r.Msg() != line {
+			t.Errorf("expected %q to be passed through, got %+v", line, r)
+		}
+	}
+
+	p = NewJsonParser(JsonParseConfig{MessageKeys: []string{"text"}})
+	r = Record{Line: []byte(`{"text":"hello","msg":"other","time":"bogus"}`)}
+	p.Parse(&r)
+
+	if r.Message != "hello" || r.Fields["msg"] != "other" || r.Fields["time"] != "bogus" {
+		t.Errorf("unexpected record parsed with custom keys: %+v", r)
+	}
+
+	p = NewJsonParser(JsonParseConfig{})
+	r = Record{Line: []byte(`{"severity":[1],"message":{"a":"b"},"@timestamp":"bogus"}`)}
+	p.Parse(&r)
+
+	if _, ok := r.Fields["severity"]; !ok || len(r.Fields) != 3 || r.Fields["message"] == nil || r.Fields["@timestamp"] != "bogus" {
+		t.Errorf("expected values which cannot be extracted to keep their keys, got %v", r.Fields)
+	}
+}
+
+func TestParseJsonTime(t *testing.T) {
+	expected := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, ts := range []string{
+		"1451703845",
+		"1451703845.0",
+		"1451703845000",
+		"1451703845000.0",
+		"1451703845000000",
+		"1451703845000000000",
+		"1451703845000000000.0",
+		`"2016-01-02T03:04:05Z"`,
+	} {
+		r := Record{Line: []byte(`{"time":` + ts + `}`)}
+		NewJsonParser(JsonParseConfig{}).Parse(&r)
+
+		if !r.Time.Equal(expected) {
+			t.Errorf("expected timestamp %s to be %s, got %s", ts, expected, r.Time)
+		}
+	}
+
+	if _, err := parseJsonTime(json.Number("1e300")); err == nil {
+		t.Errorf("expected error parsing timestamp out of range")
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	for level, expected := range map[string]syslog.Priority{
+		"ERROR": syslog.LOG_ERR,
+		"warn":  syslog.LOG_WARNING,
+		"30":    syslog.LOG_INFO,
+		"fatal": syslog.LOG_CRIT,
+	} {
+		if severity, ok := SyslogSeverity(level); !ok || severity != expected {
+			t.Errorf("expected severity %d for %s, got %d", expected, level, severity)
+		}
+	}
+
+	if _, ok := SyslogSeverity("bogus"); ok {
+		t.Errorf("expected unknown level to have no severity")
+	}
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// Output factory.
+//
+// Creates an output from a URL. URLs which the factory does not accept should
+// be reported as an InvalidURLError.
+type Factory func(u *url.URL) (Output, error)
+
+// Invalid output URL error.
+//
+// Distinguishes URLs which cannot be opened as given from failures to set up
+// an output.
+type InvalidURLError struct {
+	Msg string
+}
+
+func (e *InvalidURLError) Error() string {
+	return e.Msg
+}
+
+// Invalid output URL error.
+func InvalidURLErrorf(format string, a ...interface{}) error {
+	return &InvalidURLError{fmt.Sprintf(format, a...)}
+}
+
+// Registered output factories by scheme.
+var (
+	factoriesMutex sync.RWMutex
+	factories      = make(map[string]Factory)
+)
+
+// Register an output factory for a URL scheme.
+//
+// Panics if the factory is nil or a factory is already registered for the
+// scheme.
+func Register(scheme string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if factory == nil {
+		panic("output: register of nil factory for scheme " + scheme)
+	}
+	if _, dup := factories[scheme]; dup {
+		panic("output: register called twice for scheme " + scheme)
+	}
+
+	factories[scheme] = factory
+}
+
+// Open an output from a URL.
+//
+// The output is created by the factory registered for the scheme of the URL.
+// The following schemes are registered by default:
+//
+//	stdout:
+//	tcp://<host>:<port>/<token>
+//	tcps://<host>:<port>/<token>
+//	syslog://[<host>:<port>][?facility=<facility>][&tag=<tag>][&network=udp|tcp]
+//
+// tcp and tcps create token-based TCP outputs, with tcps using TLS. syslog
+// creates a syslog output, sending to the local syslog daemon unless a host
+// is provided.
+//
+// Lines are formatted as given by the format, time-layout and utc query
+// parameters of any URL, which are removed before the URL is passed to the
+// factory. See NewFormattedOutput.
+//
+// Malformed URLs, unknown schemes and URLs not accepted by the factory are
+// reported as an InvalidURLError.
+func Open(rawurl string) (Output, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, InvalidURLErrorf("%s", err)
+	}
+
+	if u.Scheme == "" {
+		return nil, InvalidURLErrorf("no scheme in output URL: %s", rawurl)
+	}
+
+	factoriesMutex.RLock()
+	factory, ok := factories[u.Scheme]
+	factoriesMutex.RUnlock()
+
+	if !ok {
+		return nil, InvalidURLErrorf("unknown output scheme: %s", u.Scheme)
+	}
+
+	query := u.Query()
+	formatConfig, err := parseFormatQuery(query)
+	if err != nil {
+		return nil, InvalidURLErrorf("%s", err)
+	}
+	u.RawQuery = query.Encode()
+
+	o, err := factory(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFormattedOutput(o, formatConfig), nil
+}
@@ -0,0 +1,106 @@
+package output
+
+import (
+	"errors"
+	"log/syslog"
+	"net/url"
+	"testing"
+)
+
+// Output recording sunk lines.
+type recordingOutput struct {
+	url   *url.URL
+	lines []string
+}
+
+func (o *recordingOutput) Sink(line []byte) {
+	o.lines = append(o.lines, string(line))
+}
+
+func (o *recordingOutput) Close() error {
+	return nil
+}
+
+// Unregister the output factory for a URL scheme.
+func unregister(scheme string) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	delete(factories, scheme)
+}
+
+func TestOpen(t *testing.T) {
+	Register("test", func(u *url.URL) (Output, error) {
+		return &recordingOutput{url: u}, nil
+	})
+	defer unregister("test")
+
+	o, err := Open("test://example.com/path?option=value")
+	if err != nil {
+		t.Fatalf("unexpected error opening output: %s", err)
+	}
+
+	r, ok := o.(*recordingOutput)
+	if !ok {
+		t.Fatalf("expected output created by the registered factory, got %T", o)
+	}
+	if r.url.Host != "example.com" || r.url.Path != "/path" || r.url.Query().Get("option") != "value" {
+		t.Errorf("unexpected URL passed to factory: %s", r.url)
+	}
+
+	for _, rawurl := range []string{
+		"unknown://example.com",
+		"no-scheme",
+		"test://example.com?format=bogus",
+		"tcp://example.com:10000",
+		"tcps:///token",
+		"syslog:?facility=BOGUS",
+		"syslog://example.com:514?network=unix",
+	} {
+		if _, err := Open(rawurl); err == nil {
+			t.Errorf("expected error opening %s", rawurl)
+		} else if _, ok := err.(*InvalidURLError); !ok {
+			t.Errorf("expected invalid URL error opening %s, got %T: %s", rawurl, err, err)
+		}
+	}
+
+	failure := errors.New("connection refused")
+	Register("test-failing", func(u *url.URL) (Output, error) {
+		return nil, failure
+	})
+	defer unregister("test-failing")
+
+	if _, err := Open("test-failing://example.com"); err != failure {
+		t.Errorf("expected error of factory to be returned unchanged, got %v", err)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected registering a scheme twice to panic")
+		}
+	}()
+
+	Register("stdout", func(u *url.URL) (Output, error) {
+		return nil, nil
+	})
+}
+
+func TestParseSyslogFacility(t *testing.T) {
+	for name, expected := range map[string]syslog.Priority{
+		"":       syslog.LOG_LOCAL0,
+		"local3": syslog.LOG_LOCAL3,
+		"DAEMON": syslog.LOG_DAEMON,
+	} {
+		if facility, err := ParseSyslogFacility(name); err != nil {
+			t.Errorf("unexpected error parsing facility %q: %s", name, err)
+		} else if facility != expected {
+			t.Errorf("expected facility %q to be %d, got %d", name, expected, facility)
+		}
+	}
+
+	if _, err := ParseSyslogFacility("LOCAL8"); err == nil {
+		t.Errorf("expected error parsing invalid facility")
+	}
+}
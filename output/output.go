@@ -1,5 +1,10 @@
 package output
 
+import (
+	"fmt"
+	"time"
+)
+
 // Output.
 //
 // Receives output lines and sinks them.
@@ -13,3 +18,54 @@ type Output interface {
 	// Close the output.
 	Close() error
 }
+
+// Output which can be closed within a timeout.
+type TimeoutCloser interface {
+	// Close the output within a timeout.
+	//
+	// Lines not sunk within the timeout are abandoned, in which case a
+	// *DrainTimeoutError is returned. A timeout of zero waits indefinitely.
+	CloseTimeout(timeout time.Duration) error
+}
+
+// Output which may write to a terminal.
+type TerminalOutput interface {
+	// Test if the output writes to a terminal.
+	//
+	// Lines sunk to a terminal may contain ANSI escape sequences, such as
+	// colors.
+	IsTerminal() bool
+}
+
+// Test if an output writes to a terminal.
+func IsTerminal(o Output) bool {
+	if to, ok := o.(TerminalOutput); ok {
+		return to.IsTerminal()
+	}
+
+	return false
+}
+
+// Drain timeout error.
+type DrainTimeoutError struct {
+	// Number of lines abandoned.
+	Abandoned int
+
+	// Timeout.
+	Timeout time.Duration
+}
+
+func (e *DrainTimeoutError) Error() string {
+	return fmt.Sprintf("abandoned %d line(s) not drained within %s", e.Abandoned, e.Timeout)
+}
+
+// Close an output within a timeout.
+//
+// Outputs which cannot be closed within a timeout are closed normally.
+func CloseTimeout(o Output, timeout time.Duration) error {
+	if tc, ok := o.(TimeoutCloser); ok {
+		return tc.CloseTimeout(timeout)
+	}
+
+	return o.Close()
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"time"
+)
+
+// Record.
+//
+// A line along with details of where it came from.
+type Record struct {
+	// Time the line was read.
+	Time time.Time
+
+	// Stream the line was read from, such as stdout or stderr. Empty for
+	// lines which did not come from a stream.
+	Stream string
+
+	// Name of the process which wrote the line, if its lines are sunk to an
+	// output shared with other processes.
+	Process string
+
+	// Command which wrote the line, if any.
+	Cmd []string
+
+	// Line.
+	Line []byte
+
+	// Level of the line, such as info or error, if known.
+	Level string
+
+	// Message of the line, if extracted from the line by parsing.
+	Message string
+
+	// Fields of the line, if parsed from the line, excluding any level,
+	// message and time extracted. Nil if the line was not parsed.
+	Fields map[string]interface{}
+}
+
+// Message of the record.
+//
+// The extracted message for parsed lines, and otherwise the line.
+func (r *Record) Msg() string {
+	if r.Fields != nil {
+		return r.Message
+	}
+
+	return string(r.Line)
+}
+
+// Output which sinks records.
+type RecordOutput interface {
+	Output
+
+	// Sink a record.
+	//
+	// Must never block, like Sink.
+	SinkRecord(r Record)
+}
+
+// Output which includes the process of records in the lines it sinks.
+type ProcessOutput interface {
+	// Test if the output includes the process of records in lines.
+	//
+	// The lines of records sunk to other outputs must identify the process
+	// themselves.
+	IncludesProcess() bool
+}
+
+// Test if an output includes the process of records in the lines it sinks.
+func IncludesProcess(o Output) bool {
+	if po, ok := o.(ProcessOutput); ok {
+		return po.IncludesProcess()
+	}
+
+	return false
+}
+
+// Sink a record to an output.
+//
+// Outputs which do not sink records are sunk the line of the record.
+func SinkRecord(o Output, r Record) {
+	if ro, ok := o.(RecordOutput); ok {
+		ro.SinkRecord(r)
+	} else {
+		o.Sink(r.Line)
+	}
+}
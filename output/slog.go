@@ -0,0 +1,21 @@
+//go:build go1.21
+// +build go1.21
+
+package output
+
+import (
+	"log/slog"
+)
+
+// New slog handler sinking records to an output.
+//
+// Each record is sunk as a single line of key=value pairs, like
+// slog.TextHandler, for example:
+//
+//	time=2006-01-02T15:04:05.000Z level=INFO msg="listening" port=8080
+//
+// For lines of JSON, use slog.NewJSONHandler with Writer instead. The output
+// is not closed by the handler.
+func NewSlogHandler(o Output, opts *slog.HandlerOptions) slog.Handler {
+	return slog.NewTextHandler(&outputWriter{output: o}, opts)
+}
@@ -0,0 +1,27 @@
+//go:build go1.21
+// +build go1.21
+
+package output
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	o := &recordingOutput{}
+	logger := slog.New(NewSlogHandler(o, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	logger.With("component", "http").Info("listening\non port", "port", 8080)
+
+	if expected := `level=INFO msg="listening\non port" component=http port=8080`; len(o.lines) != 1 || o.lines[0] != expected {
+		t.Errorf("expected line %q, got %q", expected, o.lines)
+	}
+}
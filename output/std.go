@@ -1,9 +1,16 @@
 package output
 
 import (
+	"net/url"
 	"os"
 )
 
+func init() {
+	Register("stdout", func(u *url.URL) (Output, error) {
+		return NewStdoutOutput()
+	})
+}
+
 // New stdout output.
 func NewStdoutOutput() (Output, error) {
 	return newFileOutput(os.Stdout)
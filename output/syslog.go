@@ -3,12 +3,169 @@ package output
 import (
 	"fmt"
 	"log/syslog"
+	"net/url"
 	"os"
+	"strings"
 )
 
+// Syslog facilities by name.
+var syslogFacilities = map[string]syslog.Priority{
+	"KERN":     syslog.LOG_KERN,
+	"USER":     syslog.LOG_USER,
+	"MAIL":     syslog.LOG_MAIL,
+	"DAEMON":   syslog.LOG_DAEMON,
+	"AUTH":     syslog.LOG_AUTH,
+	"SYSLOG":   syslog.LOG_SYSLOG,
+	"LPR":      syslog.LOG_LPR,
+	"NEWS":     syslog.LOG_NEWS,
+	"UUCP":     syslog.LOG_UUCP,
+	"CRON":     syslog.LOG_CRON,
+	"AUTHPRIV": syslog.LOG_AUTHPRIV,
+	"FTP":      syslog.LOG_FTP,
+	"LOCAL0":   syslog.LOG_LOCAL0,
+	"LOCAL1":   syslog.LOG_LOCAL1,
+	"LOCAL2":   syslog.LOG_LOCAL2,
+	"LOCAL3":   syslog.LOG_LOCAL3,
+	"LOCAL4":   syslog.LOG_LOCAL4,
+	"LOCAL5":   syslog.LOG_LOCAL5,
+	"LOCAL6":   syslog.LOG_LOCAL6,
+	"LOCAL7":   syslog.LOG_LOCAL7,
+}
+
+// Syslog severities by level.
+//
+// Levels include the numeric levels of Bunyan and pino.
+var syslogSeverities = map[string]syslog.Priority{
+	"emerg":       syslog.LOG_EMERG,
+	"emergency":   syslog.LOG_EMERG,
+	"panic":       syslog.LOG_EMERG,
+	"alert":       syslog.LOG_ALERT,
+	"crit":        syslog.LOG_CRIT,
+	"critical":    syslog.LOG_CRIT,
+	"fatal":       syslog.LOG_CRIT,
+	"err":         syslog.LOG_ERR,
+	"error":       syslog.LOG_ERR,
+	"warn":        syslog.LOG_WARNING,
+	"warning":     syslog.LOG_WARNING,
+	"notice":      syslog.LOG_NOTICE,
+	"info":        syslog.LOG_INFO,
+	"information": syslog.LOG_INFO,
+	"debug":       syslog.LOG_DEBUG,
+	"trace":       syslog.LOG_DEBUG,
+	"10":          syslog.LOG_DEBUG,
+	"20":          syslog.LOG_DEBUG,
+	"30":          syslog.LOG_INFO,
+	"40":          syslog.LOG_WARNING,
+	"50":          syslog.LOG_ERR,
+	"60":          syslog.LOG_CRIT,
+}
+
+// Marker of lines sunk to syslog outputs without a severity.
+//
+// Lines are queued prefixed by a byte holding their severity.
+const syslogNoSeverity = 0xff
+
+func init() {
+	Register("syslog", openSyslogOutput)
+}
+
+// Syslog severity of a level.
+//
+// Levels are names such as warn or error, matched case insensitively, or the
+// numeric levels of Bunyan and pino. Returns false for unknown levels.
+func SyslogSeverity(level string) (syslog.Priority, bool) {
+	severity, ok := syslogSeverities[strings.ToLower(level)]
+	return severity, ok
+}
+
+// Write a message with a severity to a syslog writer.
+func writeSyslogSeverity(w *syslog.Writer, severity syslog.Priority, m string) error {
+	switch severity {
+	case syslog.LOG_EMERG:
+		return w.Emerg(m)
+	case syslog.LOG_ALERT:
+		return w.Alert(m)
+	case syslog.LOG_CRIT:
+		return w.Crit(m)
+	case syslog.LOG_ERR:
+		return w.Err(m)
+	case syslog.LOG_WARNING:
+		return w.Warning(m)
+	case syslog.LOG_NOTICE:
+		return w.Notice(m)
+	case syslog.LOG_INFO:
+		return w.Info(m)
+	default:
+		return w.Debug(m)
+	}
+}
+
+// Syslog output.
+type syslogOutput struct {
+	*drainingOutput
+}
+
+func (o *syslogOutput) Sink(line []byte) {
+	o.drainingOutput.Sink(append([]byte{syslogNoSeverity}, line...))
+}
+
+func (o *syslogOutput) SinkRecord(r Record) {
+	var severity byte = syslogNoSeverity
+	if s, ok := SyslogSeverity(r.Level); ok {
+		severity = byte(s)
+	}
+
+	o.drainingOutput.Sink(append([]byte{severity}, r.Line...))
+}
+
+// Parse a syslog facility name.
+//
+// Names are case insensitive. An empty name defaults to LOCAL0.
+func ParseSyslogFacility(name string) (syslog.Priority, error) {
+	if name == "" {
+		return syslog.LOG_LOCAL0, nil
+	}
+
+	facility, ok := syslogFacilities[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("invalid syslog facility: %s", name)
+	}
+
+	return facility, nil
+}
+
+// Open a syslog output from a URL.
+//
+// The URL is of the form
+// syslog://[<host>:<port>][?facility=<facility>][&tag=<tag>][&network=udp|tcp].
+func openSyslogOutput(u *url.URL) (Output, error) {
+	query := u.Query()
+
+	facility, err := ParseSyslogFacility(query.Get("facility"))
+	if err != nil {
+		return nil, InvalidURLErrorf("%s", err)
+	}
+
+	var network string
+	if u.Host != "" {
+		switch network = query.Get("network"); network {
+		case "":
+			network = "udp"
+		case "udp", "tcp":
+		default:
+			return nil, InvalidURLErrorf("invalid network for syslog output: %s", network)
+		}
+	} else if query.Get("network") != "" {
+		return nil, InvalidURLErrorf("no host specified for syslog output")
+	}
+
+	return NewSyslogOutput(network, u.Host, facility, query.Get("tag"))
+}
+
 // New syslog TCP output.
 //
-// If the network is empty, the local syslog daemon will be used.
+// If the network is empty, the local syslog daemon will be used. Records with
+// a known level are sent with the severity of the level.
 func NewSyslogOutput(network, raddr string, priority syslog.Priority, tag string) (Output, error) {
 	desc := "syslog"
 	if network != "" {
@@ -38,7 +195,7 @@ func NewSyslogOutput(network, raddr string, priority syslog.Priority, tag string
 		return nil
 	}
 
-	return newDrainingOutput(10240, func(lines [][]byte) error {
+	o, err := newDrainingOutput(10240, func(lines [][]byte) error {
 		// Connect if a connection does not already exist.
 		if w == nil {
 			if err := dial(); err != nil {
@@ -50,11 +207,19 @@ func NewSyslogOutput(network, raddr string, priority syslog.Priority, tag string
 		first := true
 
 		for _, l := range lines {
-			if len(l) == 0 {
+			severity, m := l[0], l[1:]
+			if len(m) == 0 {
 				continue
 			}
 
-			n, err := w.Write(l)
+			var n int
+			var err error
+
+			if severity == syslogNoSeverity {
+				n, err = w.Write(m)
+			} else if err = writeSyslogSeverity(w, syslog.Priority(severity), string(m)); err == nil {
+				n = len(m)
+			}
 
 			// If the first send fails without sending any data, let's attempt
 			// to reconnect.
@@ -87,4 +252,9 @@ func NewSyslogOutput(network, raddr string, priority syslog.Priority, tag string
 			w.Close()
 		}
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &syslogOutput{o.(*drainingOutput)}, nil
 }
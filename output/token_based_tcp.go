@@ -4,10 +4,44 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
+// Default timeout of connecting to token-based TCP endpoints.
+const defaultTokenBasedTcpTimeout = 5 * time.Second
+
+func init() {
+	Register("tcp", openTokenBasedTcpOutput)
+	Register("tcps", openTokenBasedTcpOutput)
+}
+
+// Open a token-based TCP output from a URL.
+//
+// The URL is of the form tcp[s]://<host>:<port>/<token>[?timeout=<duration>].
+func openTokenBasedTcpOutput(u *url.URL) (Output, error) {
+	if u.Host == "" {
+		return nil, InvalidURLErrorf("no host specified for token-based TCP output")
+	}
+
+	token := strings.TrimPrefix(u.Path, "/")
+	if token == "" {
+		return nil, InvalidURLErrorf("no token specified for token-based TCP output")
+	}
+
+	timeout := defaultTokenBasedTcpTimeout
+	if v := u.Query().Get("timeout"); v != "" {
+		var err error
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+			return nil, InvalidURLErrorf("invalid timeout for token-based TCP output: %s", v)
+		}
+	}
+
+	return NewTokenBasedTcpOutput(u.Host, token, timeout, u.Scheme == "tcps")
+}
+
 // New token based TCP output.
 //
 // Compatible with the Logentries token-based TCP data ingestion protocol:
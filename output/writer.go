@@ -0,0 +1,69 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Output writer.
+type outputWriter struct {
+	output Output
+	buf    []byte
+	mutex  sync.Mutex
+}
+
+// Sink a line, stripping any trailing CR.
+func (w *outputWriter) sink(line []byte) {
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+
+	// Outputs may hold on to lines, so they cannot share the buffer.
+	w.output.Sink(append([]byte(nil), line...))
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.buf = append(w.buf, p...)
+
+	for {
+		newlinePos := bytes.IndexByte(w.buf, '\n')
+		if newlinePos == -1 {
+			break
+		}
+
+		w.sink(w.buf[:newlinePos])
+		w.buf = w.buf[newlinePos+1:]
+	}
+
+	// Release the consumed part of the buffer.
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+
+	return len(p), nil
+}
+
+func (w *outputWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.buf) > 0 {
+		w.sink(w.buf)
+		w.buf = nil
+	}
+
+	return w.output.Close()
+}
+
+// Writer writing to an output.
+//
+// Written data is split into lines on LF, stripping any trailing CR, and each
+// line is sunk to the output. Writes never block and never fail. Closing the
+// writer sinks any unterminated line and closes the output.
+func Writer(o Output) io.WriteCloser {
+	return &outputWriter{output: o}
+}
@@ -0,0 +1,29 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	o := &recordingOutput{}
+	w := Writer(o)
+
+	for _, s := range []string{"first\r\nsec", "ond\n", "\nthird"} {
+		if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("unexpected write result: %d, %v", n, err)
+		}
+	}
+
+	if expected := []string{"first", "second", ""}; !reflect.DeepEqual(o.lines, expected) {
+		t.Errorf("expected lines %q before closing, got %q", expected, o.lines)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing writer: %s", err)
+	}
+
+	if expected := []string{"first", "second", "", "third"}; !reflect.DeepEqual(o.lines, expected) {
+		t.Errorf("expected lines %q after closing, got %q", expected, o.lines)
+	}
+}
@@ -0,0 +1,52 @@
+package coyote
+
+import (
+	"github.com/nickbruun/coyote/utils"
+	"sync"
+)
+
+// Number of output lines included in error reports.
+const outputTailLines = 20
+
+// Output tail.
+//
+// Output which keeps the last lines sunk, so they can be included in error
+// reports.
+type outputTail struct {
+	lines *utils.ByteSliceBuffer
+	mutex sync.Mutex
+}
+
+func (t *outputTail) Sink(line []byte) {
+	t.mutex.Lock()
+	t.lines.Add(line)
+	t.mutex.Unlock()
+}
+
+func (t *outputTail) Close() error {
+	return nil
+}
+
+// Lines.
+//
+// Returns the buffered lines, oldest first.
+func (t *outputTail) Lines() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	lines := t.lines.Slices()
+	strs := make([]string, len(lines))
+
+	for i, l := range lines {
+		strs[i] = string(l)
+	}
+
+	return strs
+}
+
+// New output tail.
+func newOutputTail(size int) *outputTail {
+	return &outputTail{
+		lines: utils.NewByteSliceBuffer(size),
+	}
+}
@@ -0,0 +1,543 @@
+package coyote
+
+import (
+	"fmt"
+	"github.com/nickbruun/coyote/output"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Time given to stragglers to stop before being killed if no stop timeout is
+// configured.
+const defaultOrphanStopTimeout = 10 * time.Second
+
+// Time waited for output to be drained after the process group is empty.
+//
+// Output pipes can be held open by processes which have left the process
+// group, in which case draining is aborted after this time.
+const drainGracePeriod = 5 * time.Second
+
+// Names of the output streams of processes, in the order of their readers.
+//
+// Output of processes attached to a pseudo-terminal is read as stdout.
+var processStreams = []string{"stdout", "stderr"}
+
+// Orphan policy.
+//
+// Decides what happens to processes left in the process group after the main
+// process has exited.
+type OrphanPolicy int
+
+const (
+	// Terminate orphans, killing them if they do not stop in time.
+	OrphanTerm OrphanPolicy = iota
+
+	// Kill orphans.
+	OrphanKill
+
+	// Wait for orphans to exit.
+	OrphanWait
+)
+
+// Process exit.
+type processExit struct {
+	// Wait status.
+	status syscall.WaitStatus
+
+	// Error from waiting for the process, if any.
+	waitErr error
+
+	// Last signal forwarded to the process.
+	lastSig os.Signal
+
+	// Reason the process was stopped by the supervisor, if any.
+	stopReason string
+
+	// Whether the process was killed by the OOM killer.
+	oomKilled bool
+
+	// Wall-clock duration of the process.
+	duration time.Duration
+
+	// Resource usage of the process, if available.
+	rusage *syscall.Rusage
+}
+
+// Exit status.
+//
+// -1 if the process was terminated by a signal. A process stopped by the
+// supervisor never exits with status 0.
+func (e *processExit) exitStatus() int {
+	if e.waitErr != nil {
+		return 1
+	}
+
+	if e.stopReason != "" && e.status.Exited() && e.status.ExitStatus() == 0 {
+		return 1
+	}
+
+	return e.status.ExitStatus()
+}
+
+// Error describing how the process exited.
+//
+// Returns nil if the process exited with status 0 without being stopped by
+// the supervisor.
+func (e *processExit) err() error {
+	err := e.statusErr()
+
+	if e.stopReason != "" {
+		if err != nil {
+			return fmt.Errorf("%s: %s", e.stopReason, err)
+		}
+
+		return fmt.Errorf("%s", e.stopReason)
+	}
+
+	return err
+}
+
+// Error describing the wait status.
+func (e *processExit) statusErr() error {
+	if e.waitErr != nil {
+		return e.waitErr
+	}
+
+	if e.status.Exited() {
+		if e.status.ExitStatus() == 0 {
+			return nil
+		}
+
+		return fmt.Errorf("exit status %d", e.status.ExitStatus())
+	}
+
+	if e.status.Signaled() {
+		if e.oomKilled {
+			return fmt.Errorf("out of memory: killed by the OOM killer")
+		}
+
+		if e.status.CoreDump() {
+			return fmt.Errorf("signal: %s (core dumped)", e.status.Signal())
+		}
+
+		return fmt.Errorf("signal: %s", e.status.Signal())
+	}
+
+	return fmt.Errorf("unexpected wait status: %#x", uint32(e.status))
+}
+
+// Process.
+//
+// Child process running in its own process group, with stdout and stderr
+// drained to outputs.
+type process struct {
+	args    []string
+	cmd     *exec.Cmd
+	outputs []output.Output
+	config  SupervisorConfig
+	cgroup  *cgroup
+
+	// Called when the process is considered stalled, unless stalled
+	// processes are stopped.
+	onStall func(reason string)
+
+	pid          int
+	startedAt    time.Time
+	winch        chan os.Signal
+	readers      []*os.File
+	drainWg      sync.WaitGroup
+	mutex        sync.Mutex
+	lastSig      os.Signal
+	killTimer    *time.Timer
+	killTimeout  time.Duration
+	timeoutTimer *time.Timer
+	watchdog     *outputWatchdog
+	stopReason   string
+	exited       bool
+}
+
+// Start the process.
+func (p *process) start() error {
+	p.cmd = exec.Command(p.args[0], p.args[1:]...)
+	p.cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    true,
+		Credential: p.config.Credential,
+	}
+	p.cmd.Dir = p.config.Dir
+	p.cmd.Env = p.config.Env
+
+	var writers []*os.File
+
+	if p.config.Pty {
+		// Attach the process to a pseudo-terminal, through which all of its
+		// output is read.
+		master, slave, err := openPty()
+		if err != nil {
+			return fmt.Errorf("failed to set up pseudo-terminal: %s", err)
+		}
+
+		if err = copyWinsize(master, os.Stdin); err != nil {
+			master.Close()
+			slave.Close()
+			return fmt.Errorf("failed to set pseudo-terminal window size: %s", err)
+		}
+
+		// Input should not end up in the output.
+		if err = disableEcho(slave); err != nil {
+			master.Close()
+			slave.Close()
+			return fmt.Errorf("failed to disable pseudo-terminal echo: %s", err)
+		}
+
+		p.readers = []*os.File{master}
+		writers = []*os.File{slave}
+
+		// Without forwarding, stdin is empty rather than the terminal, so
+		// the terminal is made the controlling terminal through stdout.
+		if p.config.Stdin {
+			p.cmd.Stdin = slave
+		}
+		p.cmd.Stdout = slave
+		p.cmd.Stderr = slave
+		setControllingTerminal(p.cmd.SysProcAttr, 1)
+	} else {
+		if p.config.Stdin {
+			if IsTerminal(os.Stdin) {
+				return fmt.Errorf("stdin is a terminal, which can only be forwarded through a pseudo-terminal")
+			}
+
+			p.cmd.Stdin = os.Stdin
+		}
+
+		// Set up pipes for output. The parent's copies of the write ends are
+		// closed once the process has started, so draining finishes when
+		// every process holding them has exited.
+		for i := 0; i < 2; i++ {
+			r, w, err := os.Pipe()
+			if err != nil {
+				p.closeFiles(p.readers)
+				p.closeFiles(writers)
+				return fmt.Errorf("failed to set up output pipe: %s", err)
+			}
+
+			p.readers = append(p.readers, r)
+			writers = append(writers, w)
+		}
+
+		p.cmd.Stdout = writers[0]
+		p.cmd.Stderr = writers[1]
+	}
+
+	// Place the process in a cgroup of its own.
+	if p.config.Cgroup.Enabled() {
+		cg, err := newCgroup(p.config.Cgroup)
+		if err != nil {
+			p.closeFiles(p.readers)
+			p.closeFiles(writers)
+			return fmt.Errorf("failed to set up cgroup: %s", err)
+		}
+
+		p.cgroup = cg
+		cg.apply(p.cmd.SysProcAttr)
+	}
+
+	err := startWithRlimits(p.cmd, p.config.Rlimits, p.config.Reaper)
+	p.closeFiles(writers)
+
+	if err != nil {
+		p.closeFiles(p.readers)
+		p.removeCgroup()
+		return err
+	}
+
+	p.pid = p.cmd.Process.Pid
+	p.startedAt = time.Now()
+
+	// Forward stdin through the pseudo-terminal, signaling end of file with
+	// the EOF character once stdin is exhausted.
+	if p.config.Pty && p.config.Stdin {
+		go func(master *os.File) {
+			if _, err := io.Copy(master, os.Stdin); err == nil {
+				master.Write([]byte{4})
+			}
+		}(p.readers[0])
+	}
+
+	// Propagate window size changes to the pseudo-terminal.
+	if p.config.Pty && IsTerminal(os.Stdin) {
+		p.winch = make(chan os.Signal, 1)
+		signal.Notify(p.winch, syscall.SIGWINCH)
+
+		go func(master *os.File) {
+			for range p.winch {
+				copyWinsize(master, os.Stdin)
+			}
+		}(p.readers[0])
+	}
+
+	// Drain output.
+	var filter func([]byte) []byte
+	if p.config.StripAnsi {
+		filter = StripAnsi
+	}
+
+	outputs := p.outputs
+
+	if p.config.OutputWatchdog > 0 {
+		p.watchdog = newOutputWatchdog(p.config.OutputWatchdog, p.stalled)
+		outputs = append(append([]output.Output(nil), p.outputs...), p.watchdog)
+	}
+
+	p.drainWg.Add(len(p.readers))
+
+	for i, r := range p.readers {
+		go func(r *os.File, stream string) {
+			drain(r, outputs, filter, p.config.JsonParser, stream, p.args)
+			p.drainWg.Done()
+		}(r, processStreams[i])
+	}
+
+	// Stop the process once the timeout is reached.
+	if p.config.Timeout > 0 {
+		p.timeoutTimer = time.AfterFunc(p.config.Timeout, func() {
+			p.stop(fmt.Sprintf("timed out after %s", p.config.Timeout))
+		})
+	}
+
+	return nil
+}
+
+// Remove the cgroup of the process, if any.
+func (p *process) removeCgroup() {
+	if p.cgroup != nil {
+		p.cgroup.remove()
+		p.cgroup = nil
+	}
+}
+
+// Close files.
+func (p *process) closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// Signal the process group.
+//
+// If the signal asks the process to stop and a stop timeout is configured, the
+// process group is killed if the process has not exited within the timeout.
+func (p *process) signal(sig os.Signal) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.exited {
+		return
+	}
+
+	if s, ok := sig.(syscall.Signal); ok {
+		syscall.Kill(-p.pid, s)
+	} else {
+		p.cmd.Process.Signal(sig)
+	}
+	p.lastSig = sig
+
+	if p.config.StopTimeout > 0 && p.killTimer == nil && IsStopSignal(sig) {
+		p.killTimeout = p.config.StopTimeout
+		p.killTimer = time.AfterFunc(p.killTimeout, p.escalate)
+	}
+}
+
+// Stop the process group for a reason.
+//
+// Sends TERM to the process group, killing it if the process has not exited
+// within the stop timeout, or 10s if no stop timeout is configured. The
+// process exit is considered a failure.
+func (p *process) stop(reason string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.exited || p.stopReason != "" {
+		return
+	}
+
+	p.stopReason = reason
+	sinkLine([]byte(fmt.Sprintf("Stopping process: %s", reason)), p.outputs)
+
+	syscall.Kill(-p.pid, syscall.SIGTERM)
+	p.lastSig = syscall.SIGTERM
+
+	if p.killTimer == nil {
+		p.killTimeout = p.config.StopTimeout
+		if p.killTimeout == 0 {
+			p.killTimeout = defaultOrphanStopTimeout
+		}
+
+		p.killTimer = time.AfterFunc(p.killTimeout, p.escalate)
+	}
+}
+
+// Handle the process being stalled.
+func (p *process) stalled() {
+	reason := fmt.Sprintf("no output for %s", p.config.OutputWatchdog)
+
+	if p.config.StopStalled {
+		p.stop(reason)
+	} else if p.onStall != nil {
+		p.onStall(reason)
+	}
+}
+
+// Kill the process group after failing to stop in time.
+func (p *process) escalate() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.exited {
+		return
+	}
+
+	sinkLine([]byte(fmt.Sprintf("Process did not stop within %s - killing it", p.killTimeout)), p.outputs)
+	syscall.Kill(-p.pid, syscall.SIGKILL)
+	p.lastSig = syscall.SIGKILL
+}
+
+// Test if any process is left in the process group.
+func (p *process) groupAlive() bool {
+	return syscall.Kill(-p.pid, 0) == nil
+}
+
+// Wait for the process group to be empty.
+//
+// Returns false if the timeout is reached first. A zero timeout waits
+// indefinitely.
+func (p *process) waitGroup(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for p.groupAlive() {
+		if timeout > 0 && time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return true
+}
+
+// Handle orphans left in the process group according to the policy.
+func (p *process) handleOrphans() {
+	if !p.groupAlive() {
+		return
+	}
+
+	switch p.config.Orphans {
+	case OrphanTerm:
+		stopTimeout := p.config.StopTimeout
+		if stopTimeout == 0 {
+			stopTimeout = defaultOrphanStopTimeout
+		}
+
+		syscall.Kill(-p.pid, syscall.SIGTERM)
+
+		if !p.waitGroup(stopTimeout) {
+			sinkLine([]byte(fmt.Sprintf("Orphaned processes did not stop within %s - killing them", stopTimeout)), p.outputs)
+			syscall.Kill(-p.pid, syscall.SIGKILL)
+		}
+
+	case OrphanKill:
+		syscall.Kill(-p.pid, syscall.SIGKILL)
+
+	case OrphanWait:
+		p.waitGroup(0)
+	}
+}
+
+// Wait for the process to exit.
+//
+// Handles any orphans left in the process group and waits for output to be
+// drained.
+func (p *process) wait() *processExit {
+	exit := &processExit{}
+
+	if p.config.Reaper != nil {
+		child := p.config.Reaper.wait(p.pid)
+		p.cmd.Process.Release()
+		exit.status = child.status
+		exit.rusage = &child.rusage
+	} else {
+		p.cmd.Wait()
+
+		if p.cmd.ProcessState != nil {
+			exit.status = p.cmd.ProcessState.Sys().(syscall.WaitStatus)
+			exit.rusage, _ = p.cmd.ProcessState.SysUsage().(*syscall.Rusage)
+		} else {
+			exit.waitErr = fmt.Errorf("failed to wait for process")
+		}
+	}
+
+	exit.duration = time.Since(p.startedAt)
+
+	p.mutex.Lock()
+	p.exited = true
+	if p.killTimer != nil {
+		p.killTimer.Stop()
+	}
+	if p.timeoutTimer != nil {
+		p.timeoutTimer.Stop()
+	}
+	exit.lastSig = p.lastSig
+	exit.stopReason = p.stopReason
+	p.mutex.Unlock()
+
+	if p.watchdog != nil {
+		p.watchdog.Close()
+	}
+
+	if p.winch != nil {
+		signal.Stop(p.winch)
+		close(p.winch)
+	}
+
+	p.handleOrphans()
+
+	// Wait for draining to finish, giving up if the pipes are held open by
+	// processes outside the process group.
+	drained := make(chan struct{})
+	go func() {
+		p.drainWg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(drainGracePeriod):
+		p.closeFiles(p.readers)
+		<-drained
+	}
+
+	p.closeFiles(p.readers)
+
+	// Tell whether the process was killed for running out of memory.
+	if p.cgroup != nil {
+		exit.oomKilled = exit.status.Signaled() && exit.status.Signal() == syscall.SIGKILL && p.cgroup.oomKilled()
+		p.removeCgroup()
+	}
+
+	return exit
+}
+
+// New process.
+//
+// If a reaper is configured, the process is waited for through the reaper.
+func newProcess(args []string, outputs []output.Output, config SupervisorConfig) *process {
+	return &process{
+		args:    args,
+		outputs: outputs,
+		config:  config,
+	}
+}
@@ -0,0 +1,119 @@
+//go:build linux
+// +build linux
+
+package coyote
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Window size.
+type winsize struct {
+	rows   uint16
+	cols   uint16
+	xpixel uint16
+	ypixel uint16
+}
+
+// Perform an ioctl on a file.
+//
+// Goes through the raw connection, so the file stays in non-blocking mode.
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var errno syscall.Errno
+	if err = conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// Open a pseudo-terminal.
+//
+// Returns the master and slave ends.
+func openPty() (master, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Unlock the slave and find its number.
+	var unlock int32
+	if err = ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to unlock pseudo-terminal: %s", err)
+	}
+
+	var n uint32
+	if err = ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("failed to get pseudo-terminal number: %s", err)
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
+
+// Test if a file is a terminal.
+func IsTerminal(f *os.File) bool {
+	var termios syscall.Termios
+	return ioctl(f, syscall.TCGETS, unsafe.Pointer(&termios)) == nil
+}
+
+// Copy the window size of a terminal to another.
+//
+// If the source is not a terminal, a default size of 80x24 is used.
+func copyWinsize(dst, src *os.File) error {
+	ws := winsize{
+		rows: 24,
+		cols: 80,
+	}
+
+	if src != nil {
+		var srcWs winsize
+		if err := ioctl(src, syscall.TIOCGWINSZ, unsafe.Pointer(&srcWs)); err == nil && srcWs.rows > 0 && srcWs.cols > 0 {
+			ws = srcWs
+		}
+	}
+
+	return ioctl(dst, syscall.TIOCSWINSZ, unsafe.Pointer(&ws))
+}
+
+// Set the terminal attributes of a process.
+//
+// Makes the process the leader of a new session with the terminal at the file
+// descriptor as its controlling terminal.
+func setControllingTerminal(attr *syscall.SysProcAttr, fd int) {
+	attr.Setpgid = false
+	attr.Setsid = true
+	attr.Setctty = true
+	attr.Ctty = fd
+}
+
+// Disable echoing of input on a terminal.
+func disableEcho(f *os.File) error {
+	var termios syscall.Termios
+	if err := ioctl(f, syscall.TCGETS, unsafe.Pointer(&termios)); err != nil {
+		return err
+	}
+
+	termios.Lflag &^= syscall.ECHO | syscall.ECHONL
+	return ioctl(f, syscall.TCSETS, unsafe.Pointer(&termios))
+}
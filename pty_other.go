@@ -0,0 +1,41 @@
+//go:build !linux
+// +build !linux
+
+package coyote
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Open a pseudo-terminal.
+//
+// Only supported on Linux.
+func openPty() (master, slave *os.File, err error) {
+	return nil, nil, fmt.Errorf("pseudo-terminals are only supported on Linux")
+}
+
+// Test if a file is a terminal.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Copy the window size of a terminal to another.
+func copyWinsize(dst, src *os.File) error {
+	return fmt.Errorf("pseudo-terminals are only supported on Linux")
+}
+
+// Set the terminal attributes of a process.
+func setControllingTerminal(attr *syscall.SysProcAttr, fd int) {
+	attr.Setpgid = false
+	attr.Setsid = true
+	attr.Setctty = true
+	attr.Ctty = fd
+}
+
+// Disable echoing of input on a terminal.
+func disableEcho(f *os.File) error {
+	return fmt.Errorf("pseudo-terminals are only supported on Linux")
+}
@@ -0,0 +1,313 @@
+//go:build linux
+// +build linux
+
+package coyote
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// prctl option for becoming a child subreaper.
+const prSetChildSubreaper = 36
+
+// waitid ID type for waiting for any child.
+const pAll = 0
+
+// Index of the PID in siginfo_t as 32-bit words, which follows the signal
+// number, error number and code, aligned to the size of a pointer.
+const siginfoPidIndex = (12 + unsafe.Sizeof(uintptr(0)) - 1) &^ (unsafe.Sizeof(uintptr(0)) - 1) / 4
+
+// Interval at which children are reaped even without receiving SIGCHLD.
+const reapInterval = time.Second
+
+// Time after which the status of a reaped child nobody waits for is
+// discarded.
+const unwaitedExpiry = time.Minute
+
+// Interval at which orphans are checked for when waiting for them to exit.
+const orphanPollInterval = 100 * time.Millisecond
+
+// Reaped child.
+type reapedChild struct {
+	status   syscall.WaitStatus
+	rusage   syscall.Rusage
+	reapedAt time.Time
+}
+
+// Reaper.
+//
+// Makes the current process a child subreaper and reaps all children outside
+// its own process group, which includes supervised processes and any orphans
+// reparented to the current process. Children in its own process group, such
+// as error hooks, are left to be waited for by whoever started them.
+type Reaper struct {
+	pid      int
+	pgid     int
+	mutex    sync.Mutex
+	waiters  map[int]chan reapedChild
+	unwaited map[int]reapedChild
+	done     chan struct{}
+
+	// Exited child in our own process group holding up reaping, and when it
+	// was first seen.
+	lingering      int
+	lingeringSince time.Time
+}
+
+// Find an exited child without reaping it.
+//
+// Returns 0 if no child has exited.
+func findExited() (int, error) {
+	var info [32]int32
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, pAll, 0, uintptr(unsafe.Pointer(&info[0])), syscall.WEXITED|syscall.WNOHANG|syscall.WNOWAIT, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+
+	return int(info[siginfoPidIndex]), nil
+}
+
+// Reap children outside our process group which have exited.
+//
+// Exited children are found one at a time, in the order they were started or
+// reparented to us, and inspected before being reaped. An exited child in our
+// own process group is left to whoever started it, which holds up reaping
+// until it has been waited for. If it is still there after the expiry of
+// unwaited children, it is assumed to be an orphan and reaped.
+func (r *Reaper) reap() {
+	for {
+		pid, err := findExited()
+		if err != nil || pid == 0 {
+			return
+		}
+
+		pgid, err := syscall.Getpgid(pid)
+		if err != nil {
+			// Waited for by someone else in the meantime.
+			continue
+		}
+
+		if pgid == r.pgid {
+			if pid != r.lingering {
+				r.lingering = pid
+				r.lingeringSince = time.Now()
+				return
+			}
+
+			if time.Since(r.lingeringSince) <= unwaitedExpiry {
+				return
+			}
+		}
+
+		var child reapedChild
+		wpid, err := syscall.Wait4(pid, &child.status, syscall.WNOHANG, &child.rusage)
+		if err != nil || wpid != pid || pgid == r.pgid {
+			continue
+		}
+
+		child.reapedAt = time.Now()
+
+		r.mutex.Lock()
+		if ch, ok := r.waiters[pid]; ok {
+			delete(r.waiters, pid)
+			ch <- child
+		} else {
+			r.unwaited[pid] = child
+		}
+		r.mutex.Unlock()
+	}
+}
+
+// Read the state, parent PID and process group of a process from procfs.
+func readProcessStat(pid int) (state string, ppid, pgid int, ok bool) {
+	data, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return "", 0, 0, false
+	}
+
+	// The command name may contain spaces and parentheses, so skip past the
+	// last closing parenthesis, after which come the state, parent PID and
+	// process group.
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 3 {
+		return "", 0, 0, false
+	}
+
+	ppid, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, 0, false
+	}
+	pgid, err = strconv.Atoi(fields[2])
+	if err != nil {
+		return "", 0, 0, false
+	}
+
+	return fields[0], ppid, pgid, true
+}
+
+// Find running children outside our process group.
+//
+// Walks procfs, so only used when stopping orphans.
+func (r *Reaper) orphans() []int {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+
+		state, ppid, pgid, ok := readProcessStat(pid)
+		if !ok || state == "Z" || ppid != r.pid || pgid == r.pgid {
+			continue
+		}
+
+		pids = append(pids, pid)
+	}
+
+	return pids
+}
+
+// Send a signal to running children outside our process group.
+func (r *Reaper) signalOrphans(sig syscall.Signal) {
+	for _, pid := range r.orphans() {
+		syscall.Kill(pid, sig)
+	}
+}
+
+// Wait for all children outside our process group to exit.
+//
+// Returns false if the timeout is reached first. A zero timeout waits
+// indefinitely.
+func (r *Reaper) waitOrphans(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for len(r.orphans()) > 0 {
+		if timeout > 0 && time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(orphanPollInterval)
+	}
+
+	return true
+}
+
+// Stop orphans.
+//
+// Handles children left outside our process group, such as daemons which left
+// the process group of a supervised process, according to the orphan policy.
+// Must only be called once supervised processes have exited.
+func (r *Reaper) StopOrphans(policy OrphanPolicy, stopTimeout time.Duration) {
+	switch policy {
+	case OrphanTerm:
+		if stopTimeout == 0 {
+			stopTimeout = defaultOrphanStopTimeout
+		}
+
+		r.signalOrphans(syscall.SIGTERM)
+
+		if !r.waitOrphans(stopTimeout) {
+			r.signalOrphans(syscall.SIGKILL)
+		}
+
+	case OrphanKill:
+		r.signalOrphans(syscall.SIGKILL)
+
+	case OrphanWait:
+		r.waitOrphans(0)
+	}
+}
+
+// Forget children reaped a while ago which nobody has waited for.
+//
+// These are assumed to be orphans.
+func (r *Reaper) expire() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for pid, child := range r.unwaited {
+		if time.Since(child.reapedAt) > unwaitedExpiry {
+			delete(r.unwaited, pid)
+		}
+	}
+}
+
+// Wait for a child to be reaped.
+func (r *Reaper) wait(pid int) reapedChild {
+	r.mutex.Lock()
+	if child, ok := r.unwaited[pid]; ok {
+		delete(r.unwaited, pid)
+		r.mutex.Unlock()
+		return child
+	}
+
+	ch := make(chan reapedChild, 1)
+	r.waiters[pid] = ch
+	r.mutex.Unlock()
+
+	return <-ch
+}
+
+// Stop reaping.
+//
+// The current process remains a child subreaper.
+func (r *Reaper) stop() {
+	close(r.done)
+}
+
+// Start reaping.
+//
+// Supervisors given the reaper wait for their processes through it, so they
+// are not reaped from under them. Only supported on Linux.
+func StartReaper() (*Reaper, error) {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+		return nil, errno
+	}
+
+	r := &Reaper{
+		pid:      os.Getpid(),
+		pgid:     syscall.Getpgrp(),
+		waiters:  make(map[int]chan reapedChild),
+		unwaited: make(map[int]reapedChild),
+		done:     make(chan struct{}),
+	}
+
+	sigchld := make(chan os.Signal, 1)
+	signal.Notify(sigchld, syscall.SIGCHLD)
+
+	go func() {
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+		defer signal.Stop(sigchld)
+
+		for {
+			select {
+			case <-sigchld:
+			case <-ticker.C:
+				r.expire()
+			case <-r.done:
+				return
+			}
+
+			r.reap()
+		}
+	}()
+
+	return r, nil
+}
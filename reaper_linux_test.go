@@ -0,0 +1,145 @@
+package coyote
+
+import (
+	"bufio"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Wait for a process to have been reaped.
+func waitForReaped(pid int, timeout time.Duration) bool {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if syscall.Kill(pid, 0) == syscall.ESRCH {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Start an orphan outside our process group.
+//
+// The script runs in a process group of its own, and prints the PID of the
+// orphan it leaves behind.
+func startOrphan(t *testing.T, r *Reaper, script string) int {
+	cmd := exec.Command("/bin/sh", "-c", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to set up stdout: %s", err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("Failed to start process: %s", err)
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read orphan PID: %s", err)
+	}
+
+	r.wait(cmd.Process.Pid)
+	cmd.Process.Release()
+
+	pid, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("Expected an orphan PID, but got %q", line)
+	}
+
+	return pid
+}
+
+func TestReaperReap(t *testing.T) {
+	r, err := StartReaper()
+	if err != nil {
+		t.Fatalf("Failed to start reaper: %s", err)
+	}
+	defer r.stop()
+
+	// Orphans of the process are reaped once they exit.
+	var orphan string
+	var mutex sync.Mutex
+
+	s := NewSupervisor([]string{"/bin/sh", "-c", "sleep 0.2 & echo $!"}, nil, SupervisorConfig{
+		Reaper:  r,
+		Orphans: OrphanWait,
+		OnLine: func(line []byte) {
+			mutex.Lock()
+			orphan = string(line)
+			mutex.Unlock()
+		},
+	})
+
+	if result := s.Run(); result == nil || result.Failed {
+		t.Fatalf("Expected the process to succeed, but got %+v", result)
+	}
+
+	mutex.Lock()
+	pid, err := strconv.Atoi(orphan)
+	mutex.Unlock()
+
+	if err != nil {
+		t.Fatalf("Expected the process to print the PID of the orphan, but got %q", orphan)
+	}
+	if !waitForReaped(pid, 5*time.Second) {
+		t.Errorf("Expected orphan %d to be reaped", pid)
+	}
+
+	// Children in our own process group are left to whoever started them,
+	// while children outside it exit around them.
+	for i := 0; i < 10; i++ {
+		other := exec.Command("/bin/true")
+		other.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if err = other.Start(); err != nil {
+			t.Fatalf("Failed to start process: %s", err)
+		}
+
+		err = exec.Command("/bin/sh", "-c", "exit 3").Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.Sys().(syscall.WaitStatus).ExitStatus() != 3 {
+			t.Errorf("Expected waiting for a child in our process group to report exit status 3, but got %v", err)
+		}
+
+		if status := r.wait(other.Process.Pid).status; !status.Exited() || status.ExitStatus() != 0 {
+			t.Errorf("Expected a child outside our process group to be reaped with exit status 0, but got %v", status)
+		}
+		other.Process.Release()
+	}
+}
+
+func TestReaperStopOrphans(t *testing.T) {
+	r, err := StartReaper()
+	if err != nil {
+		t.Fatalf("Failed to start reaper: %s", err)
+	}
+	defer r.stop()
+
+	stopped := startOrphan(t, r, "sleep 60 > /dev/null & echo $!")
+	stubborn := startOrphan(t, r, "trap '' TERM; sleep 60 > /dev/null & echo $!")
+
+	start := time.Now()
+	r.StopOrphans(OrphanTerm, 500*time.Millisecond)
+
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("Expected the orphan ignoring TERM to be killed after the stop timeout, but it was after %s", elapsed)
+	}
+
+	for _, pid := range []int{stopped, stubborn} {
+		if !waitForReaped(pid, 5*time.Second) {
+			t.Errorf("Expected orphan %d to be stopped and reaped", pid)
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+
+	killed := startOrphan(t, r, "sleep 60 > /dev/null & echo $!")
+	r.StopOrphans(OrphanKill, 0)
+
+	if !waitForReaped(killed, 5*time.Second) {
+		t.Errorf("Expected orphan %d to be killed and reaped", killed)
+		syscall.Kill(killed, syscall.SIGKILL)
+	}
+}
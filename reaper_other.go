@@ -0,0 +1,40 @@
+//go:build !linux
+// +build !linux
+
+package coyote
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// Reaped child.
+type reapedChild struct {
+	status syscall.WaitStatus
+	rusage syscall.Rusage
+}
+
+// Reaper.
+//
+// Only supported on Linux.
+type Reaper struct{}
+
+// Wait for a child to be reaped.
+func (r *Reaper) wait(pid int) reapedChild {
+	panic("reaping is not supported on this platform")
+}
+
+// Stop orphans.
+//
+// Only supported on Linux.
+func (r *Reaper) StopOrphans(policy OrphanPolicy, stopTimeout time.Duration) {
+	panic("reaping is not supported on this platform")
+}
+
+// Start reaping.
+//
+// Only supported on Linux.
+func StartReaper() (*Reaper, error) {
+	return nil, fmt.Errorf("reaping is only supported on Linux")
+}
@@ -0,0 +1,20 @@
+package coyote
+
+// Infinite resource limit.
+const RlimitInfinity = ^uint64(0)
+
+// Resource limit.
+type Rlimit struct {
+	// Resource, for example syscall.RLIMIT_NOFILE.
+	Resource int
+
+	// Resource name used in errors.
+	Name string
+
+	// Soft limit.
+	Soft uint64
+
+	// Hard limit. Ignored unless set.
+	Hard    uint64
+	HardSet bool
+}
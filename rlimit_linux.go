@@ -0,0 +1,112 @@
+//go:build linux
+// +build linux
+
+package coyote
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// Script of the shim through which processes with resource limits are
+// started.
+//
+// The shim waits for a line on fd 3 before executing the command, giving the
+// supervisor a chance to apply the limits to it. It exits without executing
+// the command if fd 3 is closed without a line being written.
+const rlimitShimScript = `read _ <&3 || exit 1; exec "$@" 3<&-`
+
+// Get and set a resource limit of a process.
+func prlimit(pid int, resource int, newLimit, oldLimit *syscall.Rlimit) error {
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource), uintptr(unsafe.Pointer(newLimit)), uintptr(unsafe.Pointer(oldLimit)), 0, 0); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// Apply resource limits to a process.
+func applyRlimits(pid int, limits []Rlimit) error {
+	for _, limit := range limits {
+		var current syscall.Rlimit
+		if err := prlimit(pid, limit.Resource, nil, &current); err != nil {
+			return fmt.Errorf("failed to get %s limit: %s", limit.Name, err)
+		}
+
+		newLimit := syscall.Rlimit{
+			Cur: limit.Soft,
+			Max: current.Max,
+		}
+		if limit.HardSet {
+			newLimit.Max = limit.Hard
+		}
+
+		if err := prlimit(pid, limit.Resource, &newLimit, nil); err != nil {
+			return fmt.Errorf("failed to set %s limit: %s", limit.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Start a command with resource limits applied to it alone.
+//
+// The command is started through a shell shim, which holds off executing the
+// command until the limits have been applied to it, so the limits of the
+// current process are never changed. A command which is found but cannot be
+// executed makes the shim exit with status 126 rather than failing to start.
+// If the limits cannot be applied, the shim is killed and waited for, through
+// the reaper if any.
+func startWithRlimits(cmd *exec.Cmd, limits []Rlimit, reaper *Reaper) error {
+	if len(limits) == 0 || cmd.Err != nil {
+		return cmd.Start()
+	}
+
+	// Fail to start commands which are missing, like exec.Cmd.Start, rather
+	// than leaving it to the shim.
+	if cmd.Dir == "" || filepath.IsAbs(cmd.Path) {
+		if _, err := exec.LookPath(cmd.Path); err != nil {
+			return err
+		}
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to set up resource limit shim: %s", err)
+	}
+	defer w.Close()
+
+	cmd.Args = append([]string{"/bin/sh", "-c", rlimitShimScript, cmd.Args[0], cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+	cmd.ExtraFiles = append([]*os.File{r}, cmd.ExtraFiles...)
+
+	err = cmd.Start()
+	r.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if err = applyRlimits(cmd.Process.Pid, limits); err != nil {
+		cmd.Process.Kill()
+
+		if reaper != nil {
+			reaper.wait(cmd.Process.Pid)
+			cmd.Process.Release()
+		} else {
+			cmd.Wait()
+		}
+
+		return err
+	}
+
+	// A failure to release the shim means it has already exited, which is
+	// reported once it is waited for.
+	w.Write([]byte{'\n'})
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package coyote
+
+import (
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestSupervisorRlimits(t *testing.T) {
+	var before, after syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &before); err != nil {
+		t.Fatalf("Failed to get the open files limit: %s", err)
+	}
+	if before.Cur <= 64 {
+		t.Skipf("Open files limit too low: %d", before.Cur)
+	}
+
+	var lines []string
+	var linesMutex sync.Mutex
+
+	s := NewSupervisor([]string{"/bin/sh", "-c", "ulimit -n"}, nil, SupervisorConfig{
+		Rlimits: []Rlimit{{Resource: syscall.RLIMIT_NOFILE, Name: "open files", Soft: 64}},
+		OnLine: func(line []byte) {
+			linesMutex.Lock()
+			lines = append(lines, string(line))
+			linesMutex.Unlock()
+		},
+	})
+
+	if result := s.Run(); result == nil || result.Failed {
+		t.Fatalf("Expected the process to succeed, but got %+v", result)
+	}
+
+	linesMutex.Lock()
+	if len(lines) != 1 || lines[0] != "64" {
+		t.Errorf("Expected the limit to apply to the process, but got %q", lines)
+	}
+	linesMutex.Unlock()
+
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &after); err != nil {
+		t.Fatalf("Failed to get the open files limit: %s", err)
+	}
+	if after != before {
+		t.Errorf("Expected the limit of the current process to be left as is, but got %+v", after)
+	}
+}
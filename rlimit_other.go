@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package coyote
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Start a command with resource limits applied to it alone.
+//
+// Only supported on Linux.
+func startWithRlimits(cmd *exec.Cmd, limits []Rlimit, reaper *Reaper) error {
+	if len(limits) != 0 {
+		return fmt.Errorf("resource limits are only supported on Linux")
+	}
+
+	return cmd.Start()
+}
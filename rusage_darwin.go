@@ -0,0 +1,12 @@
+package coyote
+
+import (
+	"syscall"
+)
+
+// Maximum resident set size in bytes.
+//
+// Reported in bytes on Darwin.
+func maxRssBytes(rusage *syscall.Rusage) int64 {
+	return int64(rusage.Maxrss)
+}
@@ -0,0 +1,15 @@
+//go:build !darwin
+// +build !darwin
+
+package coyote
+
+import (
+	"syscall"
+)
+
+// Maximum resident set size in bytes.
+//
+// Reported in kilobytes on platforms other than Darwin.
+func maxRssBytes(rusage *syscall.Rusage) int64 {
+	return int64(rusage.Maxrss) * 1024
+}
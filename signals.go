@@ -0,0 +1,75 @@
+package coyote
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Signals by name.
+var signalsByName = map[string]syscall.Signal{
+	"ABRT": syscall.SIGABRT,
+	"ALRM": syscall.SIGALRM,
+	"BUS":  syscall.SIGBUS,
+	"CHLD": syscall.SIGCHLD,
+	"CONT": syscall.SIGCONT,
+	"FPE":  syscall.SIGFPE,
+	"HUP":  syscall.SIGHUP,
+	"ILL":  syscall.SIGILL,
+	"INT":  syscall.SIGINT,
+	"KILL": syscall.SIGKILL,
+	"PIPE": syscall.SIGPIPE,
+	"PROF": syscall.SIGPROF,
+	"QUIT": syscall.SIGQUIT,
+	"SEGV": syscall.SIGSEGV,
+	"STOP": syscall.SIGSTOP,
+	"SYS":  syscall.SIGSYS,
+	"TERM": syscall.SIGTERM,
+	"TRAP": syscall.SIGTRAP,
+	"TSTP": syscall.SIGTSTP,
+	"TTIN": syscall.SIGTTIN,
+	"TTOU": syscall.SIGTTOU,
+	"URG":  syscall.SIGURG,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"XCPU": syscall.SIGXCPU,
+	"XFSZ": syscall.SIGXFSZ,
+}
+
+// Parse a signal.
+//
+// Accepts signal names with or without the SIG prefix in any case, as well as
+// signal numbers.
+func ParseSignal(value string) (syscall.Signal, error) {
+	name := strings.ToUpper(strings.TrimSpace(value))
+
+	if sig, ok := signalsByName[strings.TrimPrefix(name, "SIG")]; ok {
+		return sig, nil
+	}
+
+	if n, err := strconv.Atoi(name); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+
+	return 0, fmt.Errorf("invalid signal: %s", value)
+}
+
+// Name of a signal.
+//
+// Falls back to the signal number for signals without a known name.
+func SignalName(sig syscall.Signal) string {
+	for name, s := range signalsByName {
+		if s == sig {
+			return "SIG" + name
+		}
+	}
+
+	return strconv.Itoa(int(sig))
+}
+
+// Test if a signal asks a process to stop.
+func IsStopSignal(sig os.Signal) bool {
+	return sig == syscall.SIGTERM || sig == syscall.SIGINT || sig == syscall.SIGQUIT || sig == syscall.SIGHUP
+}
@@ -0,0 +1,340 @@
+package coyote
+
+import (
+	"fmt"
+	"github.com/nickbruun/coyote/errorhandlers"
+	"github.com/nickbruun/coyote/output"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Delay before restarting a process the first time.
+//
+// The delay doubles for every consecutive restart up to the maximum restart
+// delay, and is reset once a process has run for longer than the maximum.
+const initialRestartDelay = time.Second
+
+// Maximum delay before restarting a process.
+const maxRestartDelay = time.Minute
+
+// Restart policy.
+type RestartPolicy int
+
+const (
+	// Never restart.
+	RestartNo RestartPolicy = iota
+
+	// Restart when the process fails.
+	RestartOnFailure
+
+	// Always restart.
+	RestartAlways
+)
+
+// Test if a process should be restarted.
+func (p RestartPolicy) restart(failed bool) bool {
+	return p == RestartAlways || (p == RestartOnFailure && failed)
+}
+
+// Supervisor configuration.
+type SupervisorConfig struct {
+	// Name of the process used in error descriptions. Empty for unnamed
+	// processes.
+	Name string
+
+	// Error handler to which failures are reported. Nil disables reporting.
+	ErrorHandler errorhandlers.Handler
+
+	// Restart policy.
+	Restart RestartPolicy
+
+	// Exit policy.
+	ExitPolicy ExitPolicy
+
+	// Time given to the process to stop after forwarding a stop signal
+	// before it is killed. Zero disables killing.
+	StopTimeout time.Duration
+
+	// Orphan policy.
+	Orphans OrphanPolicy
+
+	// Run the process attached to a pseudo-terminal.
+	Pty bool
+
+	// Strip ANSI escape sequences from output.
+	StripAnsi bool
+
+	// Parser of output lines which are JSON objects. Nil disables parsing.
+	JsonParser *output.JsonParser
+
+	// Forward the stdin of the current process to the process. A terminal
+	// can only be forwarded through a pseudo-terminal, as the process runs in
+	// a process group of its own and would be stopped reading from it.
+	Stdin bool
+
+	// Time after which the process is stopped. Zero disables the timeout.
+	Timeout time.Duration
+
+	// Time without output after which the process is considered stalled.
+	// Zero disables the watchdog.
+	OutputWatchdog time.Duration
+
+	// Stop the process when stalled.
+	StopStalled bool
+
+	// Credential to run the process with. Nil runs the process as the user
+	// of the current process.
+	Credential *syscall.Credential
+
+	// Working directory. Empty runs the process in the working directory of
+	// the current process.
+	Dir string
+
+	// Environment. Nil runs the process with the environment of the current
+	// process.
+	Env []string
+
+	// Resource limits.
+	Rlimits []Rlimit
+
+	// cgroup to place the process in.
+	Cgroup CgroupConfig
+
+	// Reaper through which the process is waited for, if any.
+	Reaper *Reaper
+
+	// Called with the PID of the process every time it has started.
+	OnStart func(pid int)
+
+	// Called with the result every time the process has exited or could not
+	// be started.
+	OnExit func(result *ExitResult)
+
+	// Called with every line of output of the process. May be called
+	// concurrently for stdout and stderr.
+	OnLine func(line []byte)
+}
+
+// Output calling a function for every line.
+type lineFuncOutput func(line []byte)
+
+func (o lineFuncOutput) Sink(line []byte) {
+	o(line)
+}
+
+func (o lineFuncOutput) Close() error {
+	return nil
+}
+
+// Supervisor.
+//
+// Runs a command in its own process group, draining its stdout and stderr to
+// outputs, restarting it according to the restart policy and reporting
+// failures to the error handler. Signals are not forwarded to the process
+// unless passed to Signal or Stop.
+type Supervisor struct {
+	args    []string
+	outputs []output.Output
+	config  SupervisorConfig
+	tail    *outputTail
+
+	mutex    sync.Mutex
+	proc     *process
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// Description of the process for error reports.
+func (s *Supervisor) description() string {
+	if s.config.Name == "" {
+		return "process"
+	}
+
+	return "process " + s.config.Name
+}
+
+// Report an error.
+func (s *Supervisor) report(err error, result *ExitResult) {
+	if s.config.ErrorHandler != nil {
+		emitError(s.args, s.config.Env, err, result, s.tail, s.config.ErrorHandler)
+	}
+}
+
+// Handle the process being stalled.
+func (s *Supervisor) stalled(reason string) {
+	sinkLine([]byte(fmt.Sprintf("Process stalled: %s", reason)), s.outputs)
+	s.report(fmt.Errorf("%s stalled: %s", s.description(), reason), nil)
+}
+
+// Test if stopping.
+func (s *Supervisor) isStopping() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// Mark the supervisor as stopping.
+func (s *Supervisor) markStopping() {
+	s.stopOnce.Do(func() {
+		close(s.stopping)
+	})
+}
+
+// Signal the process group of the running process, if any.
+//
+// If the signal asks the process to stop and a stop timeout is configured,
+// the process group is killed if the process has not exited within the
+// timeout. Termination by the signal is not considered a failure.
+func (s *Supervisor) Signal(sig os.Signal) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.proc != nil {
+		s.proc.signal(sig)
+	}
+}
+
+// Stop the process with a signal.
+//
+// Like Signal, but the process is not restarted once it has exited.
+func (s *Supervisor) Stop(sig os.Signal) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.markStopping()
+
+	if s.proc != nil {
+		s.proc.signal(sig)
+	}
+}
+
+// Terminate the process for a reason.
+//
+// Sends TERM to the process group, killing it if the process has not exited
+// within the stop timeout, or 10s if no stop timeout is configured. The
+// process is not restarted, and its exit is considered a failure.
+func (s *Supervisor) Terminate(reason string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.markStopping()
+
+	if s.proc != nil {
+		s.proc.stop(reason)
+	}
+}
+
+// Run the process until it has exited for good.
+//
+// The process is restarted according to the restart policy, with restarts
+// delayed by 1s, doubling for every consecutive restart up to 1m. Returns
+// the result of the last run, or nil if the supervisor was stopped before
+// the process was started. Run may only be called once.
+func (s *Supervisor) Run() *ExitResult {
+	var result *ExitResult
+	restartDelay := initialRestartDelay
+
+	for {
+		// Start the process, unless stopping.
+		s.mutex.Lock()
+		if s.isStopping() {
+			s.mutex.Unlock()
+			return result
+		}
+
+		proc := newProcess(s.args, s.outputs, s.config)
+		proc.onStall = s.stalled
+
+		startErr := proc.start()
+		if startErr == nil {
+			s.proc = proc
+		}
+		s.mutex.Unlock()
+
+		startedAt := time.Now()
+
+		if startErr != nil {
+			result = &ExitResult{
+				ExitStatus:  1,
+				Failed:      true,
+				Err:         startErr,
+				StartFailed: true,
+			}
+
+			sinkLine([]byte(fmt.Sprintf("Unable to start process: %s", startErr)), s.outputs)
+			s.report(fmt.Errorf("unable to start %s: %s", s.description(), startErr), nil)
+		} else {
+			if s.config.OnStart != nil {
+				s.config.OnStart(proc.pid)
+			}
+
+			exit := proc.wait()
+
+			s.mutex.Lock()
+			s.proc = nil
+			s.mutex.Unlock()
+
+			result = newExitResult(exit, &s.config.ExitPolicy)
+
+			if result.Failed {
+				sinkLine([]byte(fmt.Sprintf("Process exited abnormally: %s", result.Err)), s.outputs)
+
+				if s.config.Name == "" {
+					s.report(result.Err, result)
+				} else {
+					s.report(fmt.Errorf("%s exited abnormally: %s", s.description(), result.Err), result)
+				}
+			}
+		}
+
+		if s.config.OnExit != nil {
+			s.config.OnExit(result)
+		}
+
+		if s.isStopping() || !s.config.Restart.restart(result.Failed) {
+			return result
+		}
+
+		// Restart after a delay, backing off while the process keeps exiting
+		// quickly.
+		if time.Since(startedAt) > maxRestartDelay {
+			restartDelay = initialRestartDelay
+		}
+
+		sinkLine([]byte(fmt.Sprintf("Restarting process in %s", restartDelay)), s.outputs)
+
+		select {
+		case <-time.After(restartDelay):
+		case <-s.stopping:
+			return result
+		}
+
+		if restartDelay *= 2; restartDelay > maxRestartDelay {
+			restartDelay = maxRestartDelay
+		}
+	}
+}
+
+// New supervisor.
+//
+// The supervisor runs the command given by the arguments once Run is called.
+func NewSupervisor(args []string, outputs []output.Output, config SupervisorConfig) *Supervisor {
+	s := &Supervisor{
+		args:     args,
+		config:   config,
+		tail:     newOutputTail(outputTailLines),
+		stopping: make(chan struct{}),
+	}
+
+	s.outputs = append(append([]output.Output(nil), outputs...), s.tail)
+	if config.OnLine != nil {
+		s.outputs = append(s.outputs, lineFuncOutput(config.OnLine))
+	}
+
+	return s
+}
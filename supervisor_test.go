@@ -0,0 +1,139 @@
+package coyote
+
+import (
+	"github.com/nickbruun/coyote/errorhandlers"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// Test handler recording handled errors.
+type testHandler struct {
+	handled []*errorhandlers.Error
+	mutex   sync.Mutex
+}
+
+func (h *testHandler) Handle(err *errorhandlers.Error) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.handled = append(h.handled, err)
+	return nil
+}
+
+func (h *testHandler) String() string {
+	return "test"
+}
+
+// Handled errors.
+func (h *testHandler) errors() []*errorhandlers.Error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.handled
+}
+
+func TestSupervisorRun(t *testing.T) {
+	h := &testHandler{}
+	var lines []string
+	var pid int
+	var mutex sync.Mutex
+
+	s := NewSupervisor([]string{"/bin/sh", "-c", "echo hello; exit 3"}, nil, SupervisorConfig{
+		ErrorHandler: h,
+		OnStart: func(p int) {
+			mutex.Lock()
+			pid = p
+			mutex.Unlock()
+		},
+		OnLine: func(line []byte) {
+			mutex.Lock()
+			lines = append(lines, string(line))
+			mutex.Unlock()
+		},
+	})
+
+	result := s.Run()
+	if result == nil {
+		t.Fatalf("Expected a result, but got none")
+	}
+	if result.ExitStatus != 3 || !result.Failed || result.Err == nil || result.StartFailed {
+		t.Errorf("Expected a failure with exit status 3, but got %+v", result)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if pid == 0 {
+		t.Errorf("Expected OnStart to be called with the process ID, but got %d", pid)
+	}
+	if len(lines) == 0 || lines[0] != "hello" {
+		t.Errorf("Expected OnLine to be called with the output, but got %q", lines)
+	}
+	if handled := h.errors(); len(handled) != 1 || handled[0].ExitStatus != 3 || len(handled[0].Output) == 0 {
+		t.Errorf("Expected a single error report with output, but got %+v", handled)
+	}
+}
+
+func TestSupervisorExitPolicy(t *testing.T) {
+	s := NewSupervisor([]string{"/bin/sh", "-c", "exit 3"}, nil, SupervisorConfig{
+		ExitPolicy: ExitPolicy{SuccessExitCodes: map[int]bool{3: true}},
+	})
+
+	if result := s.Run(); result == nil || result.ExitStatus != 3 || result.Failed {
+		t.Errorf("Expected a success with exit status 3, but got %+v", result)
+	}
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	runs := 0
+	var mutex sync.Mutex
+	var s *Supervisor
+
+	s = NewSupervisor([]string{"/bin/sh", "-c", "exit 1"}, nil, SupervisorConfig{
+		Restart: RestartOnFailure,
+		OnExit: func(result *ExitResult) {
+			mutex.Lock()
+			runs++
+			stop := runs == 2
+			mutex.Unlock()
+
+			if stop {
+				s.Stop(syscall.SIGTERM)
+			}
+		},
+	})
+
+	if result := s.Run(); result == nil || !result.Failed {
+		t.Errorf("Expected a failure, but got %+v", result)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if runs != 2 {
+		t.Errorf("Expected 2 runs, but got %d", runs)
+	}
+}
+
+func TestSupervisorStartFailure(t *testing.T) {
+	s := NewSupervisor([]string{"/nonexistent/command"}, nil, SupervisorConfig{})
+
+	if result := s.Run(); result == nil || !result.StartFailed || !result.Failed || result.ExitStatus != 1 {
+		t.Errorf("Expected a failure to start with exit status 1, but got %+v", result)
+	}
+}
+
+func TestSupervisorErrorEnviron(t *testing.T) {
+	h := &testHandler{}
+
+	s := NewSupervisor([]string{"/bin/sh", "-c", "exit 1"}, nil, SupervisorConfig{
+		ErrorHandler: h,
+		Env:          []string{"FOO=bar"},
+	})
+	s.Run()
+
+	if handled := h.errors(); len(handled) != 1 || len(handled[0].Environ) != 1 || handled[0].Environ["FOO"] != "bar" {
+		t.Errorf("Expected an error report with the environment of the process, but got %+v", handled)
+	}
+}
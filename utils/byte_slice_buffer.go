@@ -32,6 +32,16 @@ func (b *ByteSliceBuffer) Empty() bool {
 	return len(b.slices) == 0
 }
 
+// Slices in the buffer.
+//
+// Returns a copy of the contents of the byte slice buffer, oldest first,
+// leaving the buffer unchanged.
+func (b *ByteSliceBuffer) Slices() [][]byte {
+	slices := make([][]byte, 0, len(b.slices))
+	slices = append(slices, b.slices[b.offset:]...)
+	return append(slices, b.slices[:b.offset]...)
+}
+
 // Drain the buffer.
 //
 // Returns the contents of the byte slice buffer and resets it to zero.
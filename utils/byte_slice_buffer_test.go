@@ -34,6 +34,10 @@ func TestByteSliceBuffer(t *testing.T) {
 			expected = append(expected, []byte{byte(j)})
 		}
 
+		if actual := buf.Slices(); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected slices to be %v after adding %d element(s), but got %v", expected, i+1, actual)
+		}
+
 		actual := buf.Drain()
 		if !reflect.DeepEqual(actual, expected) {
 			t.Errorf("Expected drained slices to be %v after adding %d element(s), but got %v", expected, i+1, actual)
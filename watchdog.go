@@ -0,0 +1,45 @@
+package coyote
+
+import (
+	"sync"
+	"time"
+)
+
+// Output watchdog.
+//
+// Output which fires if no line has been sunk within the timeout. The
+// watchdog is rearmed by the next line sunk.
+type outputWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	mutex   sync.Mutex
+	closed  bool
+}
+
+func (w *outputWatchdog) Sink(line []byte) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.closed {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+func (w *outputWatchdog) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.closed = true
+	w.timer.Stop()
+	return nil
+}
+
+// New output watchdog.
+//
+// The watchdog is armed immediately.
+func newOutputWatchdog(timeout time.Duration, fire func()) *outputWatchdog {
+	return &outputWatchdog{
+		timeout: timeout,
+		timer:   time.AfterFunc(timeout, fire),
+	}
+}